@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/betacats/go-core/utils/jsonx"
+	"github.com/betacats/go-core/utils/logx"
+)
+
+const (
+	// TokenHeader 携带管理端口访问令牌的请求头，也可使用 Authorization: Bearer <token>
+	TokenHeader = "X-Admin-Token"
+	// defaultAddr 默认只监听本机回环地址
+	defaultAddr = "127.0.0.1:6060"
+)
+
+// ErrTokenRequired 监听非回环地址但没有配置令牌
+var ErrTokenRequired = errors.New("admin: token is required when listening on a non-loopback address")
+
+var logger = logx.Module("admin")
+
+type (
+	// Config 管理端口配置
+	Config struct {
+		Addr  string `json:",default=127.0.0.1:6060"`
+		Token string `json:",optional"` // 为空时不校验令牌，此时只允许监听回环地址和来自回环地址的请求
+	}
+
+	// Option 自定义管理端口
+	Option func(s *Server)
+
+	// Server 独立端口上的管理和调试服务，提供 pprof、expvar、构建信息、生效配置、
+	// closes hook 列表和日志级别调整接口，实现了 app.Component
+	Server struct {
+		config Config
+		mux    *http.ServeMux
+
+		mu        sync.RWMutex
+		effective any
+
+		srv *http.Server
+	}
+)
+
+// WithConfig 设置在 /config 中展示的生效配置，敏感字段会被脱敏
+func WithConfig(v any) Option {
+	return func(s *Server) {
+		s.effective = v
+	}
+}
+
+// WithHandler 挂载额外的处理器，如 /metrics、/healthz
+func WithHandler(pattern string, h http.Handler) Option {
+	return func(s *Server) {
+		s.mux.Handle(pattern, h)
+	}
+}
+
+// New 创建管理端口服务
+func New(c Config, opts ...Option) *Server {
+	if c.Addr == "" {
+		c.Addr = defaultAddr
+	}
+	s := &Server{config: c, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.Handle("/debug/vars", expvar.Handler())
+	s.mux.HandleFunc("GET /buildinfo", s.handleBuildInfo)
+	s.mux.HandleFunc("GET /config", s.handleConfig)
+	s.mux.HandleFunc("GET /closes", s.handleCloses)
+	s.mux.HandleFunc("GET /loglevel", s.handleGetLogLevel)
+	s.mux.HandleFunc("PUT /loglevel", s.handleSetLogLevel)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetConfig 运行时替换 /config 中展示的生效配置，如配置热更新后
+func (s *Server) SetConfig(v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.effective = v
+}
+
+// Handler 返回带令牌校验的处理器，可挂载到已有的 server 上。
+// 没有配置令牌时只允许来自回环地址的请求，其他请求返回 403；经过反向代理时 RemoteAddr 为代理地址，应配置令牌
+func (s *Server) Handler() http.Handler {
+	if s.config.Token == "" {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isLoopback(r.RemoteAddr) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			s.mux.ServeHTTP(w, r)
+		})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.Header.Get(TokenHeader)
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) == 1
+}
+
+// Start 监听端口并在后台提供服务，端口被占用等错误会直接返回。
+// 没有配置令牌时拒绝监听非回环地址，返回 ErrTokenRequired
+func (s *Server) Start(ctx context.Context) error {
+	if s.config.Token == "" && !isLoopback(s.config.Addr) {
+		return ErrTokenRequired
+	}
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	s.srv = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logger.InfoContext(ctx, "admin server listening", "addr", ln.Addr().String())
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("admin server stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop 关闭服务，等待进行中的请求完成
+func (s *Server) Stop(ctx context.Context) error {
+	if s.srv == nil {
+		return nil
+	}
+	return s.srv.Shutdown(ctx)
+}
+
+// isLoopback 判断 host:port 形式的地址是否为本机回环地址，host 为空表示监听所有网卡
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJson(w http.ResponseWriter, code int, v any) {
+	body, err := jsonx.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/utils/closes"
+	"github.com/betacats/go-core/utils/jsonx"
+	"github.com/betacats/go-core/utils/logx"
+)
+
+type dbConfig struct {
+	Dsn      string
+	Password string
+	Host     string
+}
+
+type testConfig struct {
+	Name   string
+	Mysql  dbConfig
+	Tokens []map[string]string
+}
+
+// serve 模拟来自本机的请求
+func serve(t *testing.T, h http.Handler, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.RemoteAddr = "127.0.0.1:40000"
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRedactConfig(t *testing.T) {
+	s := New(Config{}, WithConfig(testConfig{
+		Name:   "demo",
+		Mysql:  dbConfig{Dsn: "root:pass@tcp(db)/demo", Password: "pass", Host: "db"},
+		Tokens: []map[string]string{{"apiKey": "abc", "region": "cn"}},
+	}))
+
+	rec := serve(t, s.Handler(), http.MethodGet, "/config", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var res map[string]any
+	assert.Nil(t, jsonx.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, "demo", res["Name"])
+	mysql := res["Mysql"].(map[string]any)
+	assert.Equal(t, redacted, mysql["Dsn"])
+	assert.Equal(t, redacted, mysql["Password"])
+	assert.Equal(t, "db", mysql["Host"])
+	// Tokens 整体脱敏
+	assert.Equal(t, redacted, res["Tokens"])
+
+	s.SetConfig(map[string]any{"secretKey": "", "region": "cn"})
+	rec = serve(t, s.Handler(), http.MethodGet, "/config", nil)
+	assert.JSONEq(t, `{"secretKey":"","region":"cn"}`, rec.Body.String())
+}
+
+func TestToken(t *testing.T) {
+	h := New(Config{Token: "s3cret"}).Handler()
+
+	assert.Equal(t, http.StatusUnauthorized, serve(t, h, http.MethodGet, "/buildinfo", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(t, h, http.MethodGet, "/buildinfo", map[string]string{TokenHeader: "wrong"}).Code)
+	assert.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/buildinfo", map[string]string{TokenHeader: "s3cret"}).Code)
+	assert.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/debug/pprof/", map[string]string{"Authorization": "Bearer s3cret"}).Code)
+}
+
+func TestHandlerWithoutToken(t *testing.T) {
+	h := New(Config{}).Handler()
+	assert.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/buildinfo", nil).Code)
+
+	// 没有令牌时拒绝来自其他地址的请求
+	for _, remote := range []string{"10.0.0.1:40000", "[2001:db8::1]:40000"} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code, remote)
+	}
+
+	// 配置令牌后不限制来源
+	req := httptest.NewRequest(http.MethodGet, "/buildinfo", nil)
+	req.Header.Set(TokenHeader, "s3cret")
+	rec := httptest.NewRecorder()
+	New(Config{Token: "s3cret"}).Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestEndpoints(t *testing.T) {
+	h := New(Config{}, WithHandler("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	}))).Handler()
+
+	rec := serve(t, h, http.MethodGet, "/buildinfo", nil)
+	var info BuildInfo
+	assert.Nil(t, jsonx.Unmarshal(rec.Body.Bytes(), &info))
+	assert.NotEmpty(t, info.GoVersion)
+
+	assert.Contains(t, serve(t, h, http.MethodGet, "/debug/vars", nil).Body.String(), "memstats")
+	assert.Equal(t, "pong", serve(t, h, http.MethodGet, "/ping", nil).Body.String())
+
+	closes.AddShutdown(closes.ModuleClose{Name: "admin-test", Priority: closes.MQPriority, Timeout: time.Second})
+	assert.Contains(t, serve(t, h, http.MethodGet, "/closes", nil).Body.String(),
+		`{"name":"admin-test","priority":100,"timeout":"1s"}`)
+}
+
+func TestLogLevel(t *testing.T) {
+	t.Cleanup(func() { _ = logx.Apply(logx.Config{}) })
+	h := New(Config{}).Handler()
+
+	rec := serve(t, h, http.MethodPut, "/loglevel?module=kafkax&level=debug", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "debug", logx.Levels().Modules["kafkax"])
+
+	rec = serve(t, h, http.MethodGet, "/loglevel", nil)
+	var c logx.Config
+	assert.Nil(t, jsonx.Unmarshal(rec.Body.Bytes(), &c))
+	assert.Equal(t, "debug", c.Modules["kafkax"])
+
+	assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPut, "/loglevel?level=verbose", nil).Code)
+}
+
+func TestStartStop(t *testing.T) {
+	s := New(Config{Addr: "127.0.0.1:0"})
+	assert.Nil(t, s.Start(context.Background()))
+	assert.Nil(t, s.Stop(context.Background()))
+
+	assert.NotNil(t, New(Config{Addr: "127.0.0.1:-1"}).Start(context.Background()))
+}
+
+func TestLoopbackWithoutToken(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:6060", New(Config{}).config.Addr)
+
+	// 没有令牌时拒绝监听所有网卡或非回环地址
+	assert.ErrorIs(t, New(Config{Addr: ":0"}).Start(context.Background()), ErrTokenRequired)
+	assert.ErrorIs(t, New(Config{Addr: "0.0.0.0:0"}).Start(context.Background()), ErrTokenRequired)
+	assert.ErrorIs(t, New(Config{Addr: "10.0.0.1:0"}).Start(context.Background()), ErrTokenRequired)
+
+	for _, addr := range []string{"localhost:0", "[::1]:0"} {
+		assert.True(t, isLoopback(addr), addr)
+	}
+
+	s := New(Config{Addr: ":0", Token: "secret"})
+	assert.Nil(t, s.Start(context.Background()))
+	assert.Nil(t, s.Stop(context.Background()))
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/betacats/go-core/utils/closes"
+	"github.com/betacats/go-core/utils/jsonx"
+	"github.com/betacats/go-core/utils/logx"
+)
+
+// redacted 脱敏后的占位值
+const redacted = "******"
+
+// sensitiveKeys 字段名（忽略大小写）包含这些词时值会被脱敏
+var sensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "apikey", "api_key",
+	"accesskey", "access_key", "privatekey", "private_key", "credential", "dsn",
+}
+
+type (
+	// BuildInfo 构建信息
+	BuildInfo struct {
+		GoVersion   string            `json:"goVersion"`
+		Path        string            `json:"path"`
+		Version     string            `json:"version"`
+		VCSRevision string            `json:"vcsRevision,omitempty"`
+		VCSTime     string            `json:"vcsTime,omitempty"`
+		VCSModified bool              `json:"vcsModified,omitempty"`
+		Deps        map[string]string `json:"deps,omitempty"`
+	}
+
+	// hookInfo closes hook 的展示信息
+	hookInfo struct {
+		Name     string `json:"name"`
+		Priority int    `json:"priority"`
+		Timeout  string `json:"timeout,omitempty"`
+	}
+)
+
+// ReadBuildInfo 从 debug.ReadBuildInfo 读取模块版本和 VCS 信息
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Path = bi.Main.Path
+	info.Version = bi.Main.Version
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.VCSRevision = s.Value
+		case "vcs.time":
+			info.VCSTime = s.Value
+		case "vcs.modified":
+			info.VCSModified = s.Value == "true"
+		}
+	}
+	info.Deps = make(map[string]string, len(bi.Deps))
+	for _, dep := range bi.Deps {
+		info.Deps[dep.Path] = dep.Version
+	}
+	return info
+}
+
+// Redact 将 v 序列化为 JSON 后按字段名脱敏敏感值
+func Redact(v any) (any, error) {
+	body, err := jsonx.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var res any
+	if err = jsonx.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return redactValue(res), nil
+}
+
+func redactValue(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		for k, item := range vv {
+			if isSensitive(k) && item != nil && item != "" {
+				vv[k] = redacted
+				continue
+			}
+			vv[k] = redactValue(item)
+		}
+	case []any:
+		for i, item := range vv {
+			vv[i] = redactValue(item)
+		}
+	}
+	return v
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleBuildInfo(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, ReadBuildInfo())
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	v := s.effective
+	s.mu.RUnlock()
+
+	res, err := Redact(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, http.StatusOK, res)
+}
+
+func (s *Server) handleCloses(w http.ResponseWriter, r *http.Request) {
+	hooks := closes.Hooks()
+	res := make([]hookInfo, 0, len(hooks))
+	for _, h := range hooks {
+		info := hookInfo{Name: h.Name, Priority: h.Priority}
+		if h.Timeout > 0 {
+			info.Timeout = h.Timeout.String()
+		}
+		res = append(res, info)
+	}
+	writeJson(w, http.StatusOK, res)
+}
+
+func (s *Server) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, logx.Levels())
+}
+
+// handleSetLogLevel 修改日志级别，如 PUT /loglevel?module=kafkax&level=debug，
+// module 为空时修改全局级别，level 为空时删除模块的覆盖
+func (s *Server) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	module, level := r.URL.Query().Get("module"), r.URL.Query().Get("level")
+	if err := logx.SetLevel(module, level); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logger.InfoContext(r.Context(), "log level changed", "target", module, "level", level)
+	writeJson(w, http.StatusOK, logx.Levels())
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/betacats/go-core/utils/closes"
+	"github.com/betacats/go-core/utils/logx"
+)
+
+var logger = logx.Module("app")
+
+type (
+	// Component 是由 App 统一管理生命周期的组件，如 db、redis、kafka、http server 等
+	Component interface {
+		Start(ctx context.Context) error
+		Stop(ctx context.Context) error
+	}
+
+	// Hook 是 Component 的函数式适配器，未设置的函数视为空操作
+	Hook struct {
+		OnStart func(ctx context.Context) error
+		OnStop  func(ctx context.Context) error
+	}
+
+	// Option 配置 App
+	Option func(a *App)
+
+	// ComponentOption 配置单个组件
+	ComponentOption func(e *entry)
+
+	// App 按依赖和优先级顺序启动组件，启动失败时回滚已启动的组件，
+	// 收到退出信号后通过 closes 按启动的逆序关闭组件
+	App struct {
+		name          string
+		entries       []*entry
+		started       []*entry
+		startTimeout  time.Duration
+		closePriority int
+	}
+
+	entry struct {
+		name        string
+		component   Component
+		dependsOn   []string
+		priority    int
+		stopTimeout time.Duration
+		index       int
+	}
+)
+
+// Start 调用 OnStart
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+// Stop 调用 OnStop
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+// WithStartTimeout 设置 Run 启动所有组件的超时时间，默认 1 分钟
+func WithStartTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.startTimeout = d
+	}
+}
+
+// WithClosePriority 设置组件注册到 closes 时的起始优先级，默认 closes.AppPriority，即先于 closes 中的其他 hook 关闭。
+// 最后启动的组件使用起始优先级，越早启动的组件优先级越大、越晚关闭，自定义时应避开其他 hook 使用的优先级
+func WithClosePriority(p int) Option {
+	return func(a *App) {
+		a.closePriority = p
+	}
+}
+
+// DependsOn 声明组件依赖的其他组件，依赖会先于该组件启动、晚于该组件关闭
+func DependsOn(names ...string) ComponentOption {
+	return func(e *entry) {
+		e.dependsOn = append(e.dependsOn, names...)
+	}
+}
+
+// Priority 设置组件的启动优先级，没有依赖关系的组件按优先级从小到大启动，相同优先级按注册顺序
+func Priority(p int) ComponentOption {
+	return func(e *entry) {
+		e.priority = p
+	}
+}
+
+// StopTimeout 设置组件关闭的超时时间，0 表示使用 closes 的默认值
+func StopTimeout(d time.Duration) ComponentOption {
+	return func(e *entry) {
+		e.stopTimeout = d
+	}
+}
+
+// New 创建应用
+func New(name string, opts ...Option) *App {
+	a := &App{
+		name:          name,
+		startTimeout:  time.Minute,
+		closePriority: closes.AppPriority,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Add 注册组件，name 在同一个 App 中必须唯一
+func (a *App) Add(name string, c Component, opts ...ComponentOption) *App {
+	e := &entry{name: name, component: c, index: len(a.entries)}
+	for _, opt := range opts {
+		opt(e)
+	}
+	a.entries = append(a.entries, e)
+	return a
+}
+
+// Start 按顺序启动所有组件，某个组件启动失败时按逆序关闭已启动的组件并返回错误
+func (a *App) Start(ctx context.Context) error {
+	order, err := a.order()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range order {
+		logger.InfoContext(ctx, "start component", "component", e.name)
+		if err = e.component.Start(ctx); err != nil {
+			err = fmt.Errorf("start %s: %w", e.name, err)
+			// 回滚时不使用可能已经超时的 ctx
+			return errors.Join(err, a.Stop(context.WithoutCancel(ctx)))
+		}
+		a.started = append(a.started, e)
+	}
+	return nil
+}
+
+// Stop 按启动的逆序关闭已启动的组件，某个组件关闭失败不影响其他组件
+func (a *App) Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(a.started) - 1; i >= 0; i-- {
+		e := a.started[i]
+		logger.InfoContext(ctx, "stop component", "component", e.name)
+		if err := e.component.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", e.name, err))
+		}
+	}
+	a.started = nil
+	return errors.Join(errs...)
+}
+
+// Run 启动所有组件并阻塞等待退出信号，随后通过 closes 按启动的逆序关闭组件并退出进程。
+// 传给 Start 的 ctx 在所有组件关闭后才取消，组件可以用它运行后台任务。启动失败时以 closes.ExitHookFailed 退出
+func (a *App) Run() {
+	cancel, err := a.run()
+	if err == nil {
+		err = a.registerShutdown(cancel)
+	}
+	if err != nil {
+		cancel()
+		logger.Error("start failed", "app", a.name, "error", err)
+		os.Exit(closes.ExitHookFailed)
+	}
+	closes.SignalClose()
+}
+
+// run 使用保持到关闭的 ctx 启动所有组件，超过启动超时时间时取消 ctx 并回滚
+func (a *App) run() (context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(a.startTimeout, cancel)
+	err := a.Start(ctx)
+	if !timer.Stop() && err == nil {
+		// 组件忽略了 ctx 的取消，启动虽然成功但已经超时
+		err = fmt.Errorf("app: start timeout after %s", a.startTimeout)
+		err = errors.Join(err, a.Stop(context.Background()))
+	}
+	return cancel, err
+}
+
+// registerShutdown 将已启动的组件注册为 closes hook，越晚启动的组件优先级越小、越先关闭，
+// 所有组件关闭后取消传给 Start 的 ctx
+func (a *App) registerShutdown(cancel context.CancelFunc) error {
+	n := len(a.started)
+	if n+1 > closes.AppPriorityRange {
+		return fmt.Errorf("app: too many components %d", n)
+	}
+	hooks := make([]closes.ModuleClose, 0, n+1)
+	for i, e := range a.started {
+		hooks = append(hooks, closes.ModuleClose{
+			Name:     e.name,
+			Priority: a.closePriority + n - 1 - i,
+			Timeout:  e.stopTimeout,
+			Func:     e.component.Stop,
+		})
+	}
+	hooks = append(hooks, closes.ModuleClose{
+		Name:     a.name + " context",
+		Priority: a.closePriority + n,
+		Func: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+	a.started = nil
+	closes.AddShutdown(hooks...)
+	return nil
+}
+
+// order 按依赖关系拓扑排序，没有依赖关系的组件按优先级和注册顺序排列
+func (a *App) order() ([]*entry, error) {
+	byName := make(map[string]*entry, len(a.entries))
+	for _, e := range a.entries {
+		if _, ok := byName[e.name]; ok {
+			return nil, fmt.Errorf("app: duplicate component %s", e.name)
+		}
+		byName[e.name] = e
+	}
+
+	indegree := make(map[string]int, len(a.entries))
+	dependents := make(map[string][]*entry, len(a.entries))
+	for _, e := range a.entries {
+		for _, dep := range e.dependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("app: component %s depends on unknown component %s", e.name, dep)
+			}
+			indegree[e.name]++
+			dependents[dep] = append(dependents[dep], e)
+		}
+	}
+
+	var ready, order []*entry
+	for _, e := range a.entries {
+		if indegree[e.name] == 0 {
+			ready = append(ready, e)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool {
+			if ready[i].priority != ready[j].priority {
+				return ready[i].priority < ready[j].priority
+			}
+			return ready[i].index < ready[j].index
+		})
+		e := ready[0]
+		ready = ready[1:]
+		order = append(order, e)
+		for _, d := range dependents[e.name] {
+			if indegree[d.name]--; indegree[d.name] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if len(order) != len(a.entries) {
+		return nil, errors.New("app: dependency cycle between components")
+	}
+	return order, nil
+}
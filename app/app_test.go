@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/utils/closes"
+)
+
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) component(name string, startErr error) Component {
+	return Hook{
+		OnStart: func(ctx context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		OnStop: func(ctx context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	r := &recorder{}
+	a := New("demo").
+		Add("http", r.component("http", nil), DependsOn("db", "redis")).
+		Add("kafka", r.component("kafka", nil), Priority(10)).
+		Add("redis", r.component("redis", nil), Priority(1)).
+		Add("db", r.component("db", nil), Priority(1)).
+		Add("nacos", r.component("nacos", nil), Priority(-1))
+
+	ctx := context.Background()
+	assert.Nil(t, a.Start(ctx))
+	assert.Equal(t, []string{"start nacos", "start redis", "start db", "start http", "start kafka"}, r.events)
+
+	r.events = nil
+	assert.Nil(t, a.Stop(ctx))
+	assert.Equal(t, []string{"stop kafka", "stop http", "stop db", "stop redis", "stop nacos"}, r.events)
+}
+
+func TestStartRollback(t *testing.T) {
+	r := &recorder{}
+	errKafka := errors.New("kafka unavailable")
+	a := New("demo").
+		Add("db", r.component("db", nil)).
+		Add("redis", r.component("redis", nil)).
+		Add("kafka", r.component("kafka", errKafka)).
+		Add("http", r.component("http", nil))
+
+	err := a.Start(context.Background())
+	assert.ErrorIs(t, err, errKafka)
+	assert.Equal(t, []string{"start db", "start redis", "start kafka", "stop redis", "stop db"}, r.events)
+}
+
+func TestOrderError(t *testing.T) {
+	r := &recorder{}
+	ctx := context.Background()
+
+	err := New("demo").
+		Add("a", r.component("a", nil), DependsOn("b")).
+		Add("b", r.component("b", nil), DependsOn("a")).
+		Start(ctx)
+	assert.ErrorContains(t, err, "cycle")
+
+	err = New("demo").Add("a", r.component("a", nil), DependsOn("missing")).Start(ctx)
+	assert.ErrorContains(t, err, "unknown component missing")
+
+	err = New("demo").Add("a", r.component("a", nil)).Add("a", r.component("a", nil)).Start(ctx)
+	assert.ErrorContains(t, err, "duplicate component a")
+	assert.Empty(t, r.events)
+}
+
+func TestRegisterShutdown(t *testing.T) {
+	r := &recorder{}
+	a := New("demo").
+		Add("db", r.component("db", nil)).
+		Add("redis", r.component("redis", nil)).
+		Add("http", r.component("http", nil), DependsOn("db"))
+
+	var startCtx context.Context
+	a.Add("consumer", Hook{
+		OnStart: func(ctx context.Context) error {
+			startCtx = ctx
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// 关闭组件时 ctx 仍然有效，全部关闭后才取消
+			r.events = append(r.events, "stop consumer", fmt.Sprint(startCtx.Err()))
+			return nil
+		},
+	})
+
+	cancel, err := a.run()
+	assert.Nil(t, err)
+	assert.Nil(t, startCtx.Err())
+	assert.Nil(t, a.registerShutdown(cancel))
+
+	// 使用保留的优先级区间，与其他 hook 不会并行关闭
+	hooks := closes.Hooks()
+	for _, h := range hooks[:5] {
+		assert.GreaterOrEqual(t, h.Priority, closes.AppPriority)
+		assert.Less(t, h.Priority, closes.AppPriority+closes.AppPriorityRange)
+	}
+
+	r.events = nil
+	assert.Nil(t, closes.Shutdown(context.Background()))
+	assert.Equal(t, []string{"stop consumer", "<nil>", "stop http", "stop redis", "stop db"}, r.events)
+	assert.ErrorIs(t, startCtx.Err(), context.Canceled)
+}
+
+func TestRunStartTimeout(t *testing.T) {
+	r := &recorder{}
+	a := New("demo", WithStartTimeout(10*time.Millisecond)).
+		Add("db", r.component("db", nil)).
+		Add("slow", Hook{OnStart: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+
+	cancel, err := a.run()
+	defer cancel()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"start db", "stop db"}, r.events)
+}
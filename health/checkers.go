@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	rd "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+
+	"github.com/betacats/go-core/queue/kafkax"
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+// errNacosUnhealthy nacos 客户端与服务端的连接不可用
+var errNacosUnhealthy = errors.New("nacos server unhealthy")
+
+// DB 创建数据库连接检查，默认为关键依赖
+func DB(name string, db *gorm.DB) Checker {
+	return Checker{
+		Name:     name,
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
+
+// Redis 创建 redis 连接检查，默认为关键依赖
+func Redis(name string, client rd.UniversalClient) Checker {
+	return Checker{
+		Name:     name,
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
+
+// kafkaPingTTL KafkaProducer 检查结果的缓存时间，避免每次检查都重新拨号
+var kafkaPingTTL = 30 * time.Second
+
+// KafkaProducer 创建 kafka producer 检查，通过重新拨号 topic 的 leader 判断集群是否可用，
+// 结果缓存 30s，默认为关键依赖
+func KafkaProducer(name string, producer *kafkax.KafkaProducer) Checker {
+	return Checker{
+		Name:     name,
+		Critical: true,
+		Check:    cached(producer.Ping, kafkaPingTTL),
+	}
+}
+
+// cached 在 ttl 内复用 check 最近一次的结果
+func cached(check func(ctx context.Context) error, ttl time.Duration) func(ctx context.Context) error {
+	var (
+		mu        sync.Mutex
+		err       error
+		checkedAt time.Time
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checkedAt.IsZero() && time.Since(checkedAt) < ttl {
+			return err
+		}
+		err, checkedAt = check(ctx), time.Now()
+		return err
+	}
+}
+
+// Nacos 创建 nacos 连接检查，配置已缓存在本地，默认为非关键依赖
+func Nacos(name string, n *nacosx.Nacosx) Checker {
+	return Checker{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			if !n.ServerHealthy() {
+				return errNacosUnhealthy
+			}
+			return nil
+		},
+	}
+}
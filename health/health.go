@@ -0,0 +1,304 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/betacats/go-core/utils/closes"
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+const (
+	// StatusUp 检查通过
+	StatusUp = "up"
+	// StatusDown 检查失败，或关键依赖检查失败
+	StatusDown = "down"
+	// StatusDegraded 仅非关键依赖检查失败，服务仍可对外提供能力
+	StatusDegraded = "degraded"
+	// StatusPending 尚未完成第一次检查
+	StatusPending = "pending"
+)
+
+// errShuttingDown 进程开始关闭后 readiness 返回的原因
+var errShuttingDown = errors.New("shutting down")
+
+type (
+	// Checker 描述一个依赖的健康检查
+	Checker struct {
+		Name     string
+		Critical bool          // 关键依赖失败时 readiness 失败，非关键依赖失败时仅标记为 degraded
+		Interval time.Duration // 检查周期，默认 10s
+		Timeout  time.Duration // 单次检查超时时间，默认 2s
+		Check    func(ctx context.Context) error
+	}
+
+	// Result 单个依赖最近一次的检查结果
+	Result struct {
+		Name      string    `json:"name"`
+		Status    string    `json:"status"`
+		Critical  bool      `json:"critical"`
+		Error     string    `json:"error,omitempty"`
+		Latency   string    `json:"latency,omitempty"`
+		CheckedAt time.Time `json:"checkedAt,omitempty"`
+	}
+
+	// Report 汇总所有依赖的检查结果
+	Report struct {
+		Status string   `json:"status"`
+		Reason string   `json:"reason,omitempty"`
+		Checks []Result `json:"checks,omitempty"`
+	}
+
+	// Registry 管理健康检查，周期性执行检查并缓存结果，HTTP 探针只读取缓存
+	Registry struct {
+		mu       sync.RWMutex
+		checkers []Checker
+		results  map[string]Result
+		ctx      context.Context
+		cancel   context.CancelFunc
+		wg       sync.WaitGroup
+
+		shuttingDown func() bool
+	}
+)
+
+var defaultRegistry = NewRegistry()
+
+// Option 自定义注册表
+type Option func(r *Registry)
+
+// WithShutdownState 指定判断进程是否开始关闭的函数，默认为 closes.IsShuttingDown，
+// 测试中可传入独立的状态，避免受其他测试触发的关闭影响
+func WithShutdownState(shuttingDown func() bool) Option {
+	return func(r *Registry) {
+		r.shuttingDown = shuttingDown
+	}
+}
+
+// NewRegistry 创建健康检查注册表，进程开始关闭后 readiness 立即失败
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		results:      make(map[string]Result),
+		shuttingDown: closes.IsShuttingDown,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Default 返回默认注册表
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register 向默认注册表注册检查
+func Register(checkers ...Checker) {
+	defaultRegistry.Register(checkers...)
+}
+
+// Start 启动默认注册表的周期检查
+func Start(ctx context.Context) {
+	defaultRegistry.Start(ctx)
+}
+
+// Mount 在 mux 上挂载默认注册表的 /healthz 和 /readyz
+func Mount(mux *http.ServeMux) {
+	defaultRegistry.Mount(mux)
+}
+
+// Register 注册检查，Start 之后注册的检查会立即开始执行
+func (r *Registry) Register(checkers ...Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range checkers {
+		if c.Interval <= 0 {
+			c.Interval = 10 * time.Second
+		}
+		if c.Timeout <= 0 {
+			c.Timeout = 2 * time.Second
+		}
+		r.checkers = append(r.checkers, c)
+		r.results[c.Name] = Result{Name: c.Name, Status: StatusPending, Critical: c.Critical}
+		if r.ctx != nil {
+			r.run(r.ctx, c)
+		}
+	}
+}
+
+// Start 为每个检查启动后台 goroutine，立即执行一次并按 Interval 周期执行，重复调用无效
+func (r *Registry) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx != nil {
+		return
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	for _, c := range r.checkers {
+		r.run(r.ctx, c)
+	}
+}
+
+// Stop 停止所有后台检查
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.ctx, r.cancel = nil, nil
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	r.wg.Wait()
+}
+
+// run 启动单个检查的后台 goroutine，调用方需持有锁
+func (r *Registry) run(ctx context.Context, c Checker) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		for {
+			r.store(r.check(ctx, c))
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// check 执行一次检查
+func (r *Registry) check(ctx context.Context, c Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := safeCheck(ctx, c.Check)
+	res := Result{
+		Name:      c.Name,
+		Status:    StatusUp,
+		Critical:  c.Critical,
+		Latency:   time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func safeCheck(ctx context.Context, check func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("health check panic")
+		}
+	}()
+	if check == nil {
+		return nil
+	}
+	return check(ctx)
+}
+
+func (r *Registry) store(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[res.Name] = res
+}
+
+// CheckNow 立即同步执行所有检查并更新缓存，适用于启动阶段或测试
+func (r *Registry) CheckNow(ctx context.Context) Report {
+	r.mu.RLock()
+	checkers := append([]Checker(nil), r.checkers...)
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, c := range checkers {
+		wg.Add(1)
+		go func(c Checker) {
+			defer wg.Done()
+			r.store(r.check(ctx, c))
+		}(c)
+	}
+	wg.Wait()
+	return r.Readiness()
+}
+
+// Liveness 返回存活状态，只反映进程本身，依赖的检查结果仅作展示，不会导致失败
+func (r *Registry) Liveness() Report {
+	report := r.Readiness()
+	report.Status = StatusUp
+	report.Reason = ""
+	return report
+}
+
+// Readiness 根据缓存的检查结果返回就绪状态：关键依赖失败或尚未检查时为 down，
+// 仅非关键依赖失败时为 degraded，进程开始关闭后立即为 down
+func (r *Registry) Readiness() Report {
+	r.mu.RLock()
+	checks := make([]Result, 0, len(r.results))
+	for _, res := range r.results {
+		checks = append(checks, res)
+	}
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+
+	report := Report{Status: StatusUp, Checks: checks}
+	for _, res := range checks {
+		if res.Status == StatusUp {
+			continue
+		}
+		if res.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp && res.Status == StatusDown {
+			report.Status = StatusDegraded
+		}
+	}
+	if r.shuttingDown() {
+		report.Status = StatusDown
+		report.Reason = errShuttingDown.Error()
+	}
+	return report
+}
+
+// LivenessHandler 返回 /healthz 处理器，始终返回 200
+func (r *Registry) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Liveness())
+	})
+}
+
+// ReadinessHandler 返回 /readyz 处理器，状态为 down 时返回 503
+func (r *Registry) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Readiness())
+	})
+}
+
+// Mount 在 mux 上挂载 /healthz 和 /readyz
+func (r *Registry) Mount(mux *http.ServeMux) {
+	mux.Handle("/healthz", r.LivenessHandler())
+	mux.Handle("/readyz", r.ReadinessHandler())
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	body, err := jsonx.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == StatusDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/queue/kafkax"
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+func newTestRegistry(shuttingDown *atomic.Bool) *Registry {
+	return NewRegistry(WithShutdownState(shuttingDown.Load))
+}
+
+// fakeCheck 返回可在测试中修改结果的检查
+type fakeCheck struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (f *fakeCheck) set(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *fakeCheck) Check(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+func TestReadiness(t *testing.T) {
+	var (
+		shuttingDown atomic.Bool
+		db, cache    fakeCheck
+	)
+	r := newTestRegistry(&shuttingDown)
+	r.Register(
+		Checker{Name: "db", Critical: true, Check: db.Check},
+		Checker{Name: "cache", Check: cache.Check},
+	)
+
+	// 尚未检查时关键依赖视为不可用
+	assert.Equal(t, StatusDown, r.Readiness().Status)
+
+	assert.Equal(t, StatusUp, r.CheckNow(context.Background()).Status)
+
+	cache.set(errors.New("cache unavailable"))
+	report := r.CheckNow(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, "cache", report.Checks[0].Name)
+	assert.Equal(t, "cache unavailable", report.Checks[0].Error)
+
+	db.set(errors.New("db unavailable"))
+	assert.Equal(t, StatusDown, r.CheckNow(context.Background()).Status)
+	// liveness 不受依赖影响
+	assert.Equal(t, StatusUp, r.Liveness().Status)
+
+	db.set(nil)
+	cache.set(nil)
+	assert.Equal(t, StatusUp, r.CheckNow(context.Background()).Status)
+
+	shuttingDown.Store(true)
+	report = r.Readiness()
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "shutting down", report.Reason)
+}
+
+func TestCheckTimeoutAndPanic(t *testing.T) {
+	var shuttingDown atomic.Bool
+	r := newTestRegistry(&shuttingDown)
+	r.Register(
+		Checker{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		Checker{Name: "panic", Check: func(ctx context.Context) error {
+			panic("boom")
+		}},
+	)
+
+	report := r.CheckNow(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "health check panic", report.Checks[0].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[1].Error)
+}
+
+func TestStartCachesResults(t *testing.T) {
+	var (
+		shuttingDown atomic.Bool
+		calls        atomic.Int32
+	)
+	r := newTestRegistry(&shuttingDown)
+	r.Register(Checker{Name: "db", Critical: true, Interval: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}})
+
+	r.Start(context.Background())
+	defer r.Stop()
+
+	assert.Eventually(t, func() bool {
+		return r.Readiness().Status == StatusUp
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return calls.Load() >= 3
+	}, time.Second, time.Millisecond)
+
+	// 探针只读取缓存，不触发检查
+	before := calls.Load()
+	r.Stop()
+	for i := 0; i < 10; i++ {
+		r.Readiness()
+	}
+	assert.Equal(t, before, calls.Load())
+}
+
+func TestKafkaProducerChecker(t *testing.T) {
+	b := kafkax.NewMemoryBroker()
+	b.CreateTopic("orders", 1)
+	producer, err := kafkax.NewProducer(context.Background(), b, "orders")
+	assert.Nil(t, err)
+
+	ttl := kafkaPingTTL
+	kafkaPingTTL = 50 * time.Millisecond
+	t.Cleanup(func() { kafkaPingTTL = ttl })
+
+	var shuttingDown atomic.Bool
+	r := newTestRegistry(&shuttingDown)
+	r.Register(KafkaProducer("kafka", producer))
+	assert.Equal(t, StatusUp, r.CheckNow(context.Background()).Status)
+
+	// 缓存时间内不重新拨号
+	b.InjectDialError("orders", errors.New("broker unavailable"))
+	assert.Equal(t, StatusUp, r.CheckNow(context.Background()).Status)
+
+	time.Sleep(60 * time.Millisecond)
+	report := r.CheckNow(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "broker unavailable", report.Checks[0].Error)
+}
+
+func TestHandlers(t *testing.T) {
+	var shuttingDown atomic.Bool
+	r := newTestRegistry(&shuttingDown)
+	r.Register(
+		Checker{Name: "db", Critical: true, Check: func(ctx context.Context) error { return errors.New("db unavailable") }},
+	)
+	r.CheckNow(context.Background())
+
+	mux := http.NewServeMux()
+	r.Mount(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var report Report
+	assert.Nil(t, jsonx.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "db unavailable", report.Checks[0].Error)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, jsonx.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, StatusUp, report.Status)
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	rd "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+
+	"github.com/betacats/go-core/queue/kafkax"
+)
+
+// dbCollector 采集 database/sql 连接池状态
+type dbCollector struct {
+	db *sql.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewDBCollector 创建连接池状态收集器，name 作为 name 标签区分多个连接池
+func NewDBCollector(name string, db *sql.DB) prometheus.Collector {
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("db_pool_"+metric, help, nil, prometheus.Labels{LabelName: name})
+	}
+	return &dbCollector{
+		db:                db,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("open_connections", "The number of established connections both in use and idle."),
+		inUse:             desc("in_use_connections", "The number of connections currently in use."),
+		idle:              desc("idle_connections", "The number of idle connections."),
+		waitCount:         desc("wait_count_total", "The total number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns."),
+		maxIdleTimeClosed: desc("max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime."),
+	}
+}
+
+// RegisterGorm 注册 gorm 底层连接池的状态收集器
+func RegisterGorm(name string, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return Register(NewDBCollector(name, sqlDB))
+}
+
+func (c *dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(s.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(s.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(s.MaxLifetimeClosed))
+}
+
+// redisCollector 采集 redis 客户端连接池状态
+type redisCollector struct {
+	client rd.UniversalClient
+
+	hits         *prometheus.Desc
+	misses       *prometheus.Desc
+	timeouts     *prometheus.Desc
+	total        *prometheus.Desc
+	idle         *prometheus.Desc
+	stale        *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+// NewRedisCollector 创建 redis 连接池状态收集器，name 作为 name 标签区分多个客户端
+func NewRedisCollector(name string, client rd.UniversalClient) prometheus.Collector {
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("redis_pool_"+metric, help, nil, prometheus.Labels{LabelName: name})
+	}
+	return &redisCollector{
+		client:       client,
+		hits:         desc("hits_total", "The number of times a free connection was found in the pool."),
+		misses:       desc("misses_total", "The number of times a free connection was not found in the pool."),
+		timeouts:     desc("timeouts_total", "The number of times a wait timeout occurred."),
+		total:        desc("connections", "The number of total connections in the pool."),
+		idle:         desc("idle_connections", "The number of idle connections in the pool."),
+		stale:        desc("stale_connections_total", "The number of stale connections removed from the pool."),
+		waitCount:    desc("wait_count_total", "The number of times a connection was waited for."),
+		waitDuration: desc("wait_duration_seconds_total", "The total time spent waiting for a connection."),
+	}
+}
+
+func (c *redisCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.total
+	ch <- c.idle
+	ch <- c.stale
+	ch <- c.waitCount
+	ch <- c.waitDuration
+}
+
+func (c *redisCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(s.StaleConns))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, float64(s.WaitDurationNs)/1e9)
+}
+
+// kafkaProducerCollector 采集 kafkax producer 的发送统计
+type kafkaProducerCollector struct {
+	messages   *prometheus.Desc
+	bytes      *prometheus.Desc
+	errors     *prometheus.Desc
+	reconnects *prometheus.Desc
+}
+
+// NewKafkaProducerCollector 创建 kafkax producer 发送统计收集器，已默认注册
+func NewKafkaProducerCollector() prometheus.Collector {
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("kafka_producer_"+metric, help, []string{LabelTopic}, nil)
+	}
+	return &kafkaProducerCollector{
+		messages:   desc("messages_total", "The number of messages written successfully."),
+		bytes:      desc("bytes_total", "The number of key and value bytes written successfully."),
+		errors:     desc("errors_total", "The number of failed publish calls."),
+		reconnects: desc("reconnects_total", "The number of reconnects to the partition leader."),
+	}
+}
+
+func (c *kafkaProducerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.messages
+	ch <- c.bytes
+	ch <- c.errors
+	ch <- c.reconnects
+}
+
+func (c *kafkaProducerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range kafkax.AllProducerStats() {
+		ch <- prometheus.MustNewConstMetric(c.messages, prometheus.CounterValue, float64(s.Messages), s.Topic)
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(s.Bytes), s.Topic)
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(s.Errors), s.Topic)
+		ch <- prometheus.MustNewConstMetric(c.reconnects, prometheus.CounterValue, float64(s.Reconnects), s.Topic)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// routeUnmatched 未匹配到路由的请求统一使用的 route 标签值
+const routeUnmatched = "unmatched"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "Total number of HTTP requests.",
+	}, []string{LabelMethod, LabelRoute, LabelCode})
+
+	httpErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_errors_total",
+		Help: "Total number of HTTP requests that responded with a 5xx status.",
+	}, []string{LabelMethod, LabelRoute, LabelCode})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "HTTP request latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{LabelMethod, LabelRoute, LabelCode})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_server_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+)
+
+// Middleware 记录 HTTP 请求的请求数、错误数和耗时。
+// route 标签取自 http.ServeMux 匹配到的路由模板，应包裹在 ServeMux 外层
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = routeUnmatched
+		}
+		labels := prometheus.Labels{
+			LabelMethod: r.Method,
+			LabelRoute:  route,
+			LabelCode:   strconv.Itoa(rec.code),
+		}
+		httpRequests.With(labels).Inc()
+		if rec.code >= http.StatusInternalServerError {
+			httpErrors.With(labels).Inc()
+		}
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder 记录响应的状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.code = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap 供 http.ResponseController 获取底层的 ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 各组件指标统一使用的标签名
+const (
+	LabelName   = "name"   // 连接池等实例名，如 db、redis 的名称
+	LabelTopic  = "topic"  // 消息队列 topic
+	LabelMethod = "method" // HTTP 方法
+	LabelRoute  = "route"  // HTTP 路由模板，而不是原始路径，避免标签基数过高
+	LabelCode   = "code"   // HTTP 状态码
+)
+
+// registry go-core 的指标注册表，默认包含 go 运行时、进程、HTTP 和 kafkax producer 指标
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpErrors,
+		httpDuration,
+		httpInFlight,
+		NewKafkaProducerCollector(),
+	)
+}
+
+// Registry 返回 go-core 的指标注册表，业务指标也可注册到这里统一暴露
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// Register 注册指标收集器
+func Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustRegister 注册指标收集器，失败时 panic
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+// Handler 返回暴露 Prometheus 指标的 HTTP 处理器，通常挂载在 /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
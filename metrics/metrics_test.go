@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	rd "github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/queue/kafkax"
+)
+
+// fakeDriver 不建立真实连接的数据库驱动，只用于采集连接池状态
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fake driver")
+}
+
+func init() {
+	sql.Register("metrics-fake", fakeDriver{})
+}
+
+func scrape(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	h := Middleware(mux)
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(httpRequests.WithLabelValues("GET", "GET /users/{id}", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("GET", "GET /users/{id}", "500")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpErrors.WithLabelValues("GET", "GET /users/{id}", "500")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("GET", routeUnmatched, "404")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(httpInFlight))
+
+	body := scrape(t)
+	assert.Contains(t, body, `http_server_request_duration_seconds_count{code="200",method="GET",route="GET /users/{id}"} 2`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestCollectors(t *testing.T) {
+	db, err := sql.Open("metrics-fake", "")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(20)
+	defer db.Close()
+	assert.Nil(t, Register(NewDBCollector("orders", db)))
+	// 同名连接池重复注册会失败
+	assert.NotNil(t, Register(NewDBCollector("orders", db)))
+
+	client := rd.NewClient(&rd.Options{Addr: "127.0.0.1:0"})
+	defer client.Close()
+	assert.Nil(t, Register(NewRedisCollector("cache", client)))
+
+	ctx := context.Background()
+	producer, err := kafkax.NewProducer(ctx, kafkax.NewMemoryBroker(), "metrics")
+	assert.Nil(t, err)
+	assert.Nil(t, producer.Publish(ctx, []kafka.Message{{Value: []byte("abc")}}))
+
+	body := scrape(t)
+	for _, want := range []string{
+		`db_pool_max_open_connections{name="orders"} 20`,
+		`db_pool_open_connections{name="orders"} 0`,
+		`redis_pool_connections{name="cache"} 0`,
+		`kafka_producer_messages_total{topic="metrics"} 1`,
+		`kafka_producer_bytes_total{topic="metrics"} 3`,
+		`kafka_producer_errors_total{topic="metrics"} 0`,
+	} {
+		assert.True(t, strings.Contains(body, want), want)
+	}
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rd "github.com/redis/go-redis/v9"
+
+	"github.com/betacats/go-core/queue/kafkax"
+	"github.com/betacats/go-core/redis"
+)
+
+const (
+	// DriverMemory 进程内内存队列
+	DriverMemory = "memory"
+	// DriverKafka kafka
+	DriverKafka = "kafka"
+	// DriverRedis redis stream
+	DriverRedis = "redis"
+)
+
+// Config 队列配置，切换后端只需修改 Driver 及对应的连接配置
+type Config struct {
+	Driver string // memory、kafka 或 redis
+	Group  string // 消费组
+
+	Kafka       *kafkax.KafkaConfig // Driver 为 kafka 时的连接配置
+	KafkaBroker kafkax.Broker       // 可选，优先于 Kafka，测试中可传入 kafkax.MemoryBroker
+	KafkaRetry  *kafkax.RetryPolicy // 可选，配置后 Nack 的消息进入重试阶梯，否则直接进入 <topic>.dlq
+
+	Redis          *redis.RedisOption // Driver 为 redis 时的连接配置
+	RedisClient    rd.UniversalClient // 可选，优先于 Redis，复用已有客户端
+	RedisMaxLen    int64              // stream 近似最大长度，0 表示不裁剪
+	RedisClaimIdle time.Duration      // pending 消息空闲多久后被重新投递，默认 30s
+}
+
+// New 根据配置创建发布者和订阅者。
+// DriverMemory 使用进程内共享的内存队列，关闭返回的发布者和订阅者不会影响其他使用方；
+// DriverRedis 传入的 RedisClient 由调用方关闭，根据 Redis 配置创建的客户端在发布者和订阅者都关闭后关闭
+func New(ctx context.Context, c Config) (Publisher, Subscriber, error) {
+	switch c.Driver {
+	case DriverMemory:
+		return sharedMemory{defaultMemory}, sharedMemory{defaultMemory}, nil
+	case DriverKafka:
+		broker := c.KafkaBroker
+		if broker == nil {
+			if c.Kafka == nil {
+				return nil, nil, fmt.Errorf("queue: kafka config is required for driver %s", c.Driver)
+			}
+			broker = kafkax.NewBroker(c.Kafka)
+		}
+		group := c.Group
+		if group == "" && c.Kafka != nil {
+			group = c.Kafka.GroupID
+		}
+		return NewKafkaPublisher(broker), NewKafkaSubscriber(broker, group, c.KafkaRetry), nil
+	case DriverRedis:
+		client := c.RedisClient
+		var owned *ownedClient
+		if client == nil {
+			if c.Redis == nil {
+				return nil, nil, fmt.Errorf("queue: redis config is required for driver %s", c.Driver)
+			}
+			created, err := redis.NewClient(ctx, c.Redis)
+			if err != nil {
+				return nil, nil, err
+			}
+			client, owned = created, newOwnedClient(created, 2)
+		}
+		pub := NewRedisPublisher(client, c.RedisMaxLen)
+		sub := NewRedisSubscriber(client, c.Group, c.RedisClaimIdle)
+		pub.owned, sub.owned = owned, owned
+		return pub, sub, nil
+	default:
+		return nil, nil, fmt.Errorf("queue: unknown driver %q", c.Driver)
+	}
+}
+
+// sharedMemory 包装共享的内存队列，Close 不会关闭底层实例
+type sharedMemory struct {
+	*Memory
+}
+
+func (sharedMemory) Close() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package idempotent
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Record 是去重表的结构，可通过 GormStore.AutoMigrate 建表
+type Record struct {
+	Key       string    `gorm:"column:dedupe_key;primaryKey;size:191"`
+	CreatedAt time.Time `gorm:"column:created_at;index"`
+}
+
+type txKey struct{}
+
+// TxFromContext 返回 GormStore 为当前消息开启的事务，Handler 应使用它完成业务写入，
+// 使业务数据与去重记录在同一事务中提交；不在 GormStore 中执行时返回 nil
+func TxFromContext(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(txKey{}).(*gorm.DB)
+	return tx
+}
+
+// GormStore 使用 MySQL 表记录去重键，去重记录与 Handler 的写入在同一事务中提交
+type GormStore struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewGormStore 创建基于 table 表的去重存储
+func NewGormStore(db *gorm.DB, table string) *GormStore {
+	return &GormStore{db: db, table: table}
+}
+
+// AutoMigrate 创建去重表
+func (s *GormStore) AutoMigrate() error {
+	return s.db.Table(s.table).AutoMigrate(&Record{})
+}
+
+// Do 实现 Store 接口。插入去重记录与 fn 在同一事务中执行，
+// 并发处理同一 key 时后到的事务会在唯一键上等待，先到的事务提交后返回 ErrDuplicate
+func (s *GormStore) Do(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Table(s.table).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Record{Key: key, CreatedAt: time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrDuplicate
+		}
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Purge 删除 before 之前的去重记录，返回删除的条数
+func (s *GormStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Table(s.table).Where("created_at < ?", before).Delete(&Record{})
+	return res.RowsAffected, res.Error
+}
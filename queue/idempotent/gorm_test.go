@@ -0,0 +1,80 @@
+package idempotent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type payment struct {
+	ID      uint `gorm:"primaryKey"`
+	OrderID string
+}
+
+func newTestGormStore(t *testing.T) (*GormStore, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	assert.Nil(t, err)
+	store := NewGormStore(db, "dedupe_records")
+	assert.Nil(t, store.AutoMigrate())
+	assert.Nil(t, db.AutoMigrate(&payment{}))
+	return store, db
+}
+
+func count(t *testing.T, db *gorm.DB, table string) int64 {
+	var n int64
+	assert.Nil(t, db.Table(table).Count(&n).Error)
+	return n
+}
+
+func TestGormStore(t *testing.T) {
+	ctx := context.Background()
+	store, db := newTestGormStore(t)
+
+	charge := func(ctx context.Context) error {
+		tx := TxFromContext(ctx)
+		assert.NotNil(t, tx)
+		return tx.Create(&payment{OrderID: "o-1"}).Error
+	}
+	assert.Nil(t, store.Do(ctx, "o-1", charge))
+	assert.Equal(t, int64(1), count(t, db, "payments"))
+	assert.Equal(t, int64(1), count(t, db, "dedupe_records"))
+
+	// 重复投递返回 ErrDuplicate，不再执行 Handler
+	assert.ErrorIs(t, store.Do(ctx, "o-1", charge), ErrDuplicate)
+	assert.Equal(t, int64(1), count(t, db, "payments"))
+
+	// 清理过期的去重记录
+	n, err := store.Purge(ctx, time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestGormStoreRollback(t *testing.T) {
+	ctx := context.Background()
+	store, db := newTestGormStore(t)
+
+	// Handler 失败时业务写入和去重记录一起回滚
+	errFail := errors.New("downstream unavailable")
+	err := store.Do(ctx, "o-2", func(ctx context.Context) error {
+		assert.Nil(t, TxFromContext(ctx).Create(&payment{OrderID: "o-2"}).Error)
+		return errFail
+	})
+	assert.ErrorIs(t, err, errFail)
+	assert.Zero(t, count(t, db, "payments"))
+	assert.Zero(t, count(t, db, "dedupe_records"))
+
+	// 重新投递可以再次处理
+	assert.Nil(t, store.Do(ctx, "o-2", func(ctx context.Context) error {
+		return TxFromContext(ctx).Create(&payment{OrderID: "o-2"}).Error
+	}))
+	assert.Equal(t, int64(1), count(t, db, "payments"))
+}
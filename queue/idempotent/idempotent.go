@@ -0,0 +1,62 @@
+package idempotent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/betacats/go-core/queue"
+)
+
+var (
+	// ErrDuplicate 消息已处理过
+	ErrDuplicate = errors.New("idempotent: duplicate message")
+	// ErrInProgress 相同去重键的消息正在被其他消费者处理
+	ErrInProgress = errors.New("idempotent: message is being processed")
+)
+
+type (
+	// KeyFunc 从消息中提取去重键，返回空字符串表示该消息不做去重
+	KeyFunc func(msg *queue.Message) string
+
+	// Store 记录已处理的去重键
+	Store interface {
+		// Do 在 key 未处理过时执行 fn，fn 成功后记录 key；
+		// key 已处理过时不执行 fn 并返回 ErrDuplicate，fn 失败时不记录 key 以便重新投递后再次处理
+		Do(ctx context.Context, key string, fn func(ctx context.Context) error) error
+	}
+)
+
+// HeaderKey 使用消息元数据（kafka header）中的 name 作为去重键
+func HeaderKey(name string) KeyFunc {
+	return func(msg *queue.Message) string {
+		return msg.Get(name)
+	}
+}
+
+// MessageID 使用 Message.ID 作为去重键
+func MessageID() KeyFunc {
+	return func(msg *queue.Message) string {
+		return msg.ID
+	}
+}
+
+// Middleware 返回幂等消费中间件：重复的消息被直接 Ack 而不调用后续 Handler，
+// 正在被其他消费者处理的消息返回 ErrInProgress 并被 Nack
+func Middleware(store Store, key KeyFunc) queue.Middleware {
+	return func(next queue.Handler) queue.Handler {
+		return func(ctx context.Context, msg *queue.Message) error {
+			k := key(msg)
+			if k == "" {
+				return next(ctx, msg)
+			}
+
+			err := store.Do(ctx, k, func(ctx context.Context) error {
+				return next(ctx, msg)
+			})
+			if errors.Is(err, ErrDuplicate) {
+				return nil
+			}
+			return err
+		}
+	}
+}
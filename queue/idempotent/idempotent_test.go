@@ -0,0 +1,71 @@
+package idempotent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/queue"
+)
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(0)
+
+	var charged int
+	fail := true
+	h := queue.Chain(func(ctx context.Context, msg *queue.Message) error {
+		if fail {
+			fail = false
+			return errors.New("downstream unavailable")
+		}
+		charged++
+		return nil
+	}, Middleware(store, HeaderKey("order-id")))
+
+	msg := queue.NewMessage([]byte("charge"))
+	msg.Set("order-id", "o-1")
+
+	// 失败的消息不记录去重键，重新投递时再次处理
+	assert.NotNil(t, h(ctx, msg))
+	assert.Nil(t, h(ctx, msg))
+	assert.Nil(t, h(ctx, msg))
+	assert.Equal(t, 1, charged)
+
+	// 没有去重键的消息不做去重
+	assert.Nil(t, h(ctx, queue.NewMessage([]byte("charge"))))
+	assert.Nil(t, h(ctx, queue.NewMessage([]byte("charge"))))
+	assert.Equal(t, 3, charged)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(20 * time.Millisecond)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = store.Do(ctx, "k", func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	assert.ErrorIs(t, store.Do(ctx, "k", func(ctx context.Context) error { return nil }), ErrInProgress)
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		return errors.Is(store.Do(ctx, "k", func(ctx context.Context) error { return nil }), ErrDuplicate)
+	}, time.Second, time.Millisecond)
+
+	// 超过 ttl 后可再次处理
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, store.Do(ctx, "k", func(ctx context.Context) error { return nil }))
+}
+
+func TestTxFromContext(t *testing.T) {
+	assert.Nil(t, TxFromContext(context.Background()))
+}
@@ -0,0 +1,57 @@
+package idempotent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore 是进程内的去重存储，适用于测试和单实例场景
+type MemoryStore struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	keys map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	done     bool
+	expireAt time.Time
+}
+
+// NewMemoryStore 创建内存去重存储，ttl 为已处理 key 的保留时间，0 表示永久保留
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, keys: make(map[string]memoryEntry)}
+}
+
+// Do 实现 Store 接口
+func (s *MemoryStore) Do(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	s.mu.Lock()
+	entry, ok := s.keys[key]
+	if ok && !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		ok = false
+	}
+	if ok {
+		s.mu.Unlock()
+		if entry.done {
+			return ErrDuplicate
+		}
+		return ErrInProgress
+	}
+	s.keys[key] = memoryEntry{}
+	s.mu.Unlock()
+
+	err := fn(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		delete(s.keys, key)
+		return err
+	}
+	entry = memoryEntry{done: true}
+	if s.ttl > 0 {
+		entry.expireAt = time.Now().Add(s.ttl)
+	}
+	s.keys[key] = entry
+	return nil
+}
@@ -0,0 +1,74 @@
+package idempotent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/betacats/go-core/utils/logx"
+	rd "github.com/redis/go-redis/v9"
+)
+
+var logger = logx.Module("idempotent")
+
+const (
+	redisStateProcessing = "processing"
+	redisStateDone       = "done"
+)
+
+// RedisStore 使用 SETNX 记录去重键。
+// 处理期间 key 的值为 processing 并带有较短的锁过期时间，处理成功后改为 done 并使用 ttl 过期，
+// 处理失败时删除 key，保证重新投递的消息能够再次处理。
+// 处理成功但写入 done 失败时只记录日志，key 在 lockTTL 内仍为 processing
+type RedisStore struct {
+	client  rd.UniversalClient
+	prefix  string
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewRedisStore 创建 redis 去重存储，ttl 为已处理 key 的保留时间，
+// lockTTL 为处理中 key 的过期时间，应大于 Handler 的最长执行时间，默认 1 分钟
+func NewRedisStore(client rd.UniversalClient, prefix string, ttl, lockTTL time.Duration) *RedisStore {
+	if lockTTL <= 0 {
+		lockTTL = time.Minute
+	}
+	return &RedisStore{client: client, prefix: prefix, ttl: ttl, lockTTL: lockTTL}
+}
+
+// Do 实现 Store 接口
+func (s *RedisStore) Do(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	key = s.prefix + key
+	ok, err := s.client.SetNX(ctx, key, redisStateProcessing, s.lockTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		state, err := s.client.Get(ctx, key).Result()
+		if errors.Is(err, rd.Nil) {
+			// 锁恰好过期或被删除，交给重新投递处理
+			return ErrInProgress
+		}
+		if err != nil {
+			return err
+		}
+		if state == redisStateDone {
+			return ErrDuplicate
+		}
+		return ErrInProgress
+	}
+
+	if err = fn(ctx); err != nil {
+		// 使用独立的 context，避免 ctx 已取消导致 key 无法释放
+		_ = s.client.Del(context.WithoutCancel(ctx), key).Err()
+		return err
+	}
+	// fn 已成功，标记写入失败不能让消息被重新投递而重复处理，重试一次后只记录日志
+	ctx = context.WithoutCancel(ctx)
+	if err = s.client.Set(ctx, key, redisStateDone, s.ttl).Err(); err != nil {
+		if err = s.client.Set(ctx, key, redisStateDone, s.ttl).Err(); err != nil {
+			logger.ErrorContext(ctx, "mark message done failed", "key", key, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package idempotent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	rd "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := rd.NewClient(&rd.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStore(client, "dedupe:", time.Hour, 0), mr
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newTestRedisStore(t)
+
+	// 处理期间 key 为 processing，并发投递返回 ErrInProgress
+	err := store.Do(ctx, "o-1", func(ctx context.Context) error {
+		state, _ := mr.Get("dedupe:o-1")
+		assert.Equal(t, redisStateProcessing, state)
+		assert.Equal(t, time.Minute, mr.TTL("dedupe:o-1"))
+		assert.ErrorIs(t, store.Do(ctx, "o-1", func(ctx context.Context) error { return nil }), ErrInProgress)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// 处理成功后改为 done 并使用 ttl 过期，重复投递返回 ErrDuplicate
+	state, _ := mr.Get("dedupe:o-1")
+	assert.Equal(t, redisStateDone, state)
+	assert.Equal(t, time.Hour, mr.TTL("dedupe:o-1"))
+	called := false
+	err = store.Do(ctx, "o-1", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.False(t, called)
+
+	mr.FastForward(time.Hour)
+	assert.Nil(t, store.Do(ctx, "o-1", func(ctx context.Context) error { return nil }))
+}
+
+func TestRedisStoreHandlerFailure(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newTestRedisStore(t)
+
+	errFail := errors.New("downstream unavailable")
+	assert.ErrorIs(t, store.Do(ctx, "o-2", func(ctx context.Context) error { return errFail }), errFail)
+	// 失败时删除 key，重新投递可以再次处理
+	assert.False(t, mr.Exists("dedupe:o-2"))
+	assert.Nil(t, store.Do(ctx, "o-2", func(ctx context.Context) error { return nil }))
+
+	mr.SetError("server down")
+	assert.NotNil(t, store.Do(ctx, "o-3", func(ctx context.Context) error { return nil }))
+}
+
+func TestRedisStoreMarkDoneFailure(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newTestRedisStore(t)
+
+	// handler 成功后 redis 不可用，不返回错误以免消息被重新投递
+	err := store.Do(ctx, "o-4", func(ctx context.Context) error {
+		mr.SetError("server down")
+		return nil
+	})
+	assert.Nil(t, err)
+
+	mr.SetError("")
+	state, _ := mr.Get("dedupe:o-4")
+	assert.Equal(t, redisStateProcessing, state)
+	assert.ErrorIs(t, store.Do(ctx, "o-4", func(ctx context.Context) error { return nil }), ErrInProgress)
+}
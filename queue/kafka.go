@@ -0,0 +1,251 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/betacats/go-core/queue/kafkax"
+)
+
+// HeaderMessageID kafka 消息中保存 Message.ID 的 header
+const HeaderMessageID = "x-message-id"
+
+var (
+	// errNacked 是 Nack 的消息进入重试阶梯时记录的错误
+	errNacked = errors.New("queue: message nacked")
+	// errNackNotRouted Nack 的消息没有投递到重试或死信队列，拒绝提交之后的位点
+	errNackNotRouted = errors.New("queue: nacked message not routed, refusing to commit later offsets")
+)
+
+// KafkaPublisher 基于 kafkax 的发布者，Metadata 映射为消息 header
+type KafkaPublisher struct {
+	broker    kafkax.Broker
+	mu        sync.Mutex
+	producers map[string]*kafkax.KafkaProducer
+	closed    bool
+}
+
+// NewKafkaPublisher 创建 kafka 发布者
+func NewKafkaPublisher(b kafkax.Broker) *KafkaPublisher {
+	return &KafkaPublisher{
+		broker:    b,
+		producers: make(map[string]*kafkax.KafkaProducer),
+	}
+}
+
+// Publish 将消息写入 topic
+func (p *KafkaPublisher) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	producer, err := p.producer(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	kmsgs := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		kmsg := kafka.Message{Key: msg.Key, Value: msg.Payload}
+		if msg.ID != "" {
+			kmsg.Headers = append(kmsg.Headers, kafka.Header{Key: HeaderMessageID, Value: []byte(msg.ID)})
+		}
+		for k, v := range msg.Metadata {
+			kmsg.Headers = append(kmsg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		kmsgs = append(kmsgs, kmsg)
+	}
+	return producer.Publish(ctx, kmsgs)
+}
+
+func (p *KafkaPublisher) producer(ctx context.Context, topic string) (*kafkax.KafkaProducer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrClosed
+	}
+	producer, ok := p.producers[topic]
+	if !ok {
+		var err error
+		if producer, err = kafkax.NewProducer(ctx, p.broker, topic); err != nil {
+			return nil, err
+		}
+		p.producers[topic] = producer
+	}
+	return producer, nil
+}
+
+// Close 关闭所有 producer
+func (p *KafkaPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for topic, producer := range p.producers {
+		producer.Close()
+		delete(p.producers, topic)
+	}
+	return nil
+}
+
+// KafkaSubscriber 基于 kafkax 消费组的订阅者。
+// Ack 提交位点；Nack 时消息进入 kafkax 重试阶梯，未配置重试策略时直接进入 <原始 topic>.dlq，投递成功后提交位点。
+// 由于 kafka 按位点提交，Nack 的消息投递失败后，同一消费者后续的 Ack 返回错误且不提交位点，
+// 避免覆盖未处理的消息，消息会在消费者重启或再均衡后重新投递
+type KafkaSubscriber struct {
+	broker kafkax.Broker
+	group  string
+	router *kafkax.RetryRouter
+
+	mu        sync.Mutex
+	consumers []kafkax.Consumer
+	dlq       map[string]*kafkax.RetryRouter // 未配置重试策略时按死信 topic 创建的路由
+	closed    bool
+}
+
+// NewKafkaSubscriber 创建属于 group 消费组的 kafka 订阅者，retry 为 nil 时 Nack 的消息直接进入死信队列
+func NewKafkaSubscriber(b kafkax.Broker, group string, retry *kafkax.RetryPolicy) *KafkaSubscriber {
+	s := &KafkaSubscriber{broker: b, group: group, dlq: make(map[string]*kafkax.RetryRouter)}
+	if retry != nil {
+		s.router = kafkax.NewRetryRouter(b, *retry)
+	}
+	return s
+}
+
+// Subscribe 订阅 topic，也可直接订阅重试阶梯中的 topic，消息会在到期后才投递
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *Message, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrClosed
+	}
+	consumer := s.broker.NewConsumer(s.group, topic)
+	s.consumers = append(s.consumers, consumer)
+	s.mu.Unlock()
+
+	out := make(chan *Message)
+	go func() {
+		defer close(out)
+		// Nack 的消息投递失败后不再提交该消费者的位点
+		var blocked atomic.Bool
+		for {
+			kmsg, err := consumer.FetchMessage(ctx)
+			if err != nil {
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					// 获取失败时稍后重试，避免忙等
+					if sleep(ctx, time.Second) == nil {
+						continue
+					}
+				}
+				return
+			}
+			if sleep(ctx, time.Until(kafkax.RetryAt(kmsg))) != nil {
+				return
+			}
+
+			select {
+			case out <- s.deliver(consumer, kmsg, &blocked):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (s *KafkaSubscriber) deliver(consumer kafkax.Consumer, kmsg kafka.Message, blocked *atomic.Bool) *Message {
+	msg := &Message{
+		ID:        fmt.Sprintf("%s/%d/%d", kmsg.Topic, kmsg.Partition, kmsg.Offset),
+		Topic:     kmsg.Topic,
+		Key:       kmsg.Key,
+		Payload:   kmsg.Value,
+		Metadata:  make(map[string]string, len(kmsg.Headers)),
+		Timestamp: kmsg.Time,
+	}
+	for _, h := range kmsg.Headers {
+		if h.Key == HeaderMessageID {
+			msg.ID = string(h.Value)
+			continue
+		}
+		msg.Metadata[h.Key] = string(h.Value)
+	}
+
+	msg.ack = func(ctx context.Context) error {
+		if blocked.Load() {
+			return errNackNotRouted
+		}
+		return consumer.CommitMessages(ctx, kmsg)
+	}
+	msg.nack = func(ctx context.Context) error {
+		if blocked.Load() {
+			return errNackNotRouted
+		}
+		router, err := s.routerFor(kmsg)
+		if err == nil {
+			err = router.Route(ctx, kmsg, errNacked)
+		}
+		if err != nil {
+			blocked.Store(true)
+			return err
+		}
+		return consumer.CommitMessages(ctx, kmsg)
+	}
+	return msg
+}
+
+// routerFor 返回 Nack 时使用的路由，未配置重试策略时使用原始 topic 对应的死信队列
+func (s *KafkaSubscriber) routerFor(kmsg kafka.Message) (*kafkax.RetryRouter, error) {
+	if s.router != nil {
+		return s.router, nil
+	}
+
+	topic := kafkax.OriginalTopic(kmsg) + ".dlq"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	router, ok := s.dlq[topic]
+	if !ok {
+		router = kafkax.NewRetryRouter(s.broker, kafkax.RetryPolicy{DLQTopic: topic})
+		s.dlq[topic] = router
+	}
+	return router, nil
+}
+
+// Close 关闭所有消费者和重试路由
+func (s *KafkaSubscriber) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var errs []error
+	for _, c := range s.consumers {
+		errs = append(errs, c.Close())
+	}
+	s.consumers = nil
+	if s.router != nil {
+		s.router.Close()
+	}
+	for topic, router := range s.dlq {
+		router.Close()
+		delete(s.dlq, topic)
+	}
+	return errors.Join(errs...)
+}
+
+// sleep 等待 d 或 ctx 取消，d 不大于 0 时立即返回
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
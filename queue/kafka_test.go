@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/queue/kafkax"
+)
+
+func TestKafka(t *testing.T) {
+	broker := kafkax.NewMemoryBroker()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	pub, sub, err := New(ctx, Config{Driver: DriverKafka, Group: "billing", KafkaBroker: broker})
+	assert.Nil(t, err)
+	defer pub.Close()
+	defer sub.Close()
+
+	msg := NewMessage([]byte("hello"))
+	msg.ID = "order-1"
+	msg.Key = []byte("k1")
+	msg.Set("trace", "t1")
+	assert.Nil(t, pub.Publish(ctx, "orders", msg))
+
+	msgs, err := sub.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	got := <-msgs
+	assert.Equal(t, "order-1", got.ID)
+	assert.Equal(t, []byte("k1"), got.Key)
+	assert.Equal(t, []byte("hello"), got.Payload)
+	assert.Equal(t, map[string]string{"trace": "t1"}, got.Metadata)
+
+	assert.Nil(t, got.Ack(ctx))
+	assert.Equal(t, int64(1), broker.CommittedOffset("billing", "orders", 0))
+}
+
+func TestKafkaNackRetry(t *testing.T) {
+	broker := kafkax.NewMemoryBroker()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	policy := kafkax.RetryPolicy{
+		Tiers:    []kafkax.RetryTier{{Topic: "orders.retry", Delay: 20 * time.Millisecond}},
+		DLQTopic: "orders.dlq",
+	}
+	pub, sub, err := New(ctx, Config{Driver: DriverKafka, Group: "billing", KafkaBroker: broker, KafkaRetry: &policy})
+	assert.Nil(t, err)
+	defer pub.Close()
+	defer sub.Close()
+
+	assert.Nil(t, pub.Publish(ctx, "orders", NewMessage([]byte("hello"))))
+
+	msgs, err := sub.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	got := <-msgs
+	assert.Nil(t, got.Nack(ctx))
+	assert.Equal(t, int64(1), broker.CommittedOffset("billing", "orders", 0))
+
+	retries, err := sub.Subscribe(ctx, "orders.retry")
+	assert.Nil(t, err)
+	start := time.Now()
+	retried := <-retries
+	assert.Equal(t, []byte("hello"), retried.Payload)
+	assert.Equal(t, "1", retried.Get(kafkax.HeaderAttempt))
+	assert.Equal(t, "orders", retried.Get(kafkax.HeaderOriginalTopic))
+	assert.Greater(t, time.Since(start), 5*time.Millisecond)
+
+	assert.Nil(t, retried.Nack(ctx))
+	assert.Len(t, broker.Messages("orders.dlq"), 1)
+}
+
+func TestKafkaNackWithoutRetry(t *testing.T) {
+	broker := kafkax.NewMemoryBroker()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	pub, sub, err := New(ctx, Config{Driver: DriverKafka, Group: "billing", KafkaBroker: broker})
+	assert.Nil(t, err)
+	defer pub.Close()
+	defer sub.Close()
+	assert.Nil(t, pub.Publish(ctx, "orders", NewMessage([]byte("hello"))))
+
+	// 没有重试策略时 Nack 的消息进入死信队列后再提交位点
+	msgs, err := sub.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	got := <-msgs
+	assert.Nil(t, got.Nack(ctx))
+	assert.Equal(t, int64(1), broker.CommittedOffset("billing", "orders", 0))
+	dlq := broker.Messages("orders.dlq")
+	assert.Len(t, dlq, 1)
+	assert.Equal(t, []byte("hello"), dlq[0].Value)
+}
+
+func TestKafkaNackNotRouted(t *testing.T) {
+	broker := kafkax.NewMemoryBroker()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// 重试策略没有死信队列，重试次数耗尽后无法投递
+	pub, sub, err := New(ctx, Config{Driver: DriverKafka, Group: "billing", KafkaBroker: broker, KafkaRetry: &kafkax.RetryPolicy{}})
+	assert.Nil(t, err)
+	defer pub.Close()
+	defer sub.Close()
+	assert.Nil(t, pub.Publish(ctx, "orders", NewMessage([]byte("first")), NewMessage([]byte("second"))))
+
+	msgs, err := sub.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	first := <-msgs
+	assert.NotNil(t, first.Nack(ctx))
+	second := <-msgs
+	assert.ErrorIs(t, second.Ack(ctx), errNackNotRouted)
+	assert.Equal(t, int64(-1), broker.CommittedOffset("billing", "orders", 0))
+}
@@ -0,0 +1,276 @@
+package kafkax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/betacats/go-core/queue/kafkax"
+
+type (
+	// TopicSpec 描述期望存在的 topic
+	TopicSpec struct {
+		Topic             string
+		Partitions        int               // 分区数，默认 1
+		ReplicationFactor int               // 副本数，0 表示使用 broker 默认值
+		Config            map[string]string // topic 级配置，仅在创建时生效
+	}
+
+	// TopicInfo topic 及其分区
+	TopicInfo struct {
+		Topic      string
+		Partitions []int
+	}
+
+	// PartitionLag 消费组在单个分区上的积压情况
+	PartitionLag struct {
+		Topic      string
+		Partition  int
+		Committed  int64 // 已提交位点，未提交过为 -1，此时 Lag 按分区最新位点计算
+		LastOffset int64 // 分区最新位点（high watermark）
+		Lag        int64
+	}
+
+	// Admin 提供 topic 管理和消费积压查询能力
+	Admin interface {
+		// EnsureTopics 确保 topic 存在，已存在的 topic 分区不足时会扩容，可重复调用
+		EnsureTopics(ctx context.Context, specs ...TopicSpec) error
+		// ListTopics 列出集群中的 topic 及分区
+		ListTopics(ctx context.Context) ([]TopicInfo, error)
+		// ConsumerLag 计算消费组在 topic 每个分区上的积压
+		ConsumerLag(ctx context.Context, groupID, topic string) ([]PartitionLag, error)
+	}
+)
+
+// kafkaAdmin 基于 kafka.Client 的 Admin 实现
+type kafkaAdmin struct {
+	client *kafka.Client
+}
+
+// NewAdmin 根据配置创建连接真实 kafka 集群的 Admin
+func NewAdmin(c *KafkaConfig) Admin {
+	return &kafkaAdmin{
+		client: &kafka.Client{
+			Addr:    kafka.TCP(strings.Split(c.Brokers, ",")...),
+			Timeout: 10 * time.Second,
+			Transport: &kafka.Transport{
+				DialTimeout: 10 * time.Second,
+				SASL: plain.Mechanism{
+					Username: c.Username,
+					Password: c.Password,
+				},
+			},
+		},
+	}
+}
+
+func (a *kafkaAdmin) EnsureTopics(ctx context.Context, specs ...TopicSpec) error {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	topics := make([]kafka.TopicConfig, 0, len(specs))
+	for _, spec := range specs {
+		topic := kafka.TopicConfig{
+			Topic:             spec.Topic,
+			NumPartitions:     max(spec.Partitions, 1),
+			ReplicationFactor: -1,
+		}
+		if spec.ReplicationFactor > 0 {
+			topic.ReplicationFactor = spec.ReplicationFactor
+		}
+		for name, value := range spec.Config {
+			topic.ConfigEntries = append(topic.ConfigEntries, kafka.ConfigEntry{ConfigName: name, ConfigValue: value})
+		}
+		topics = append(topics, topic)
+	}
+
+	resp, err := a.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: topics})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for topic, topicErr := range resp.Errors {
+		if topicErr != nil && !errors.Is(topicErr, kafka.TopicAlreadyExists) {
+			errs = append(errs, fmt.Errorf("create topic %s: %w", topic, topicErr))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return a.growPartitions(ctx, specs)
+}
+
+// growPartitions 为分区数少于期望值的已有 topic 扩容
+func (a *kafkaAdmin) growPartitions(ctx context.Context, specs []TopicSpec) error {
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Topic)
+	}
+	meta, err := a.client.Metadata(ctx, &kafka.MetadataRequest{Topics: names})
+	if err != nil {
+		return err
+	}
+	current := make(map[string]int, len(meta.Topics))
+	for _, t := range meta.Topics {
+		current[t.Name] = len(t.Partitions)
+	}
+
+	var grow []kafka.TopicPartitionsConfig
+	for _, spec := range specs {
+		if n, ok := current[spec.Topic]; ok && n < spec.Partitions {
+			grow = append(grow, kafka.TopicPartitionsConfig{Name: spec.Topic, Count: int32(spec.Partitions)})
+		}
+	}
+	if len(grow) == 0 {
+		return nil
+	}
+
+	resp, err := a.client.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{Topics: grow})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for topic, topicErr := range resp.Errors {
+		if topicErr != nil {
+			errs = append(errs, fmt.Errorf("create partitions for topic %s: %w", topic, topicErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *kafkaAdmin) ListTopics(ctx context.Context) ([]TopicInfo, error) {
+	meta, err := a.client.Metadata(ctx, &kafka.MetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]TopicInfo, 0, len(meta.Topics))
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			return nil, fmt.Errorf("topic %s: %w", t.Name, t.Error)
+		}
+		info := TopicInfo{Topic: t.Name}
+		for _, p := range t.Partitions {
+			info.Partitions = append(info.Partitions, p.ID)
+		}
+		sort.Ints(info.Partitions)
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Topic < infos[j].Topic })
+	return infos, nil
+}
+
+func (a *kafkaAdmin) ConsumerLag(ctx context.Context, groupID, topic string) ([]PartitionLag, error) {
+	meta, err := a.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.Topics) == 0 {
+		return nil, kafka.UnknownTopicOrPartition
+	}
+	if meta.Topics[0].Error != nil {
+		return nil, meta.Topics[0].Error
+	}
+
+	var (
+		partitions []int
+		requests   []kafka.OffsetRequest
+	)
+	for _, p := range meta.Topics[0].Partitions {
+		partitions = append(partitions, p.ID)
+		requests = append(requests, kafka.LastOffsetOf(p.ID))
+	}
+
+	committed, err := a.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if committed.Error != nil {
+		return nil, committed.Error
+	}
+	offsets, err := a.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	last := make(map[int]int64, len(partitions))
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("list offsets for partition %d: %w", p.Partition, p.Error)
+		}
+		last[p.Partition] = p.LastOffset
+	}
+	lags := make([]PartitionLag, 0, len(partitions))
+	for _, p := range committed.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("fetch offset for partition %d: %w", p.Partition, p.Error)
+		}
+		lags = append(lags, newPartitionLag(topic, p.Partition, p.CommittedOffset, last[p.Partition]))
+	}
+	sort.Slice(lags, func(i, j int) bool { return lags[i].Partition < lags[j].Partition })
+	return lags, nil
+}
+
+func newPartitionLag(topic string, partition int, committed, last int64) PartitionLag {
+	lag := last
+	if committed >= 0 {
+		lag = last - committed
+	}
+	return PartitionLag{
+		Topic:      topic,
+		Partition:  partition,
+		Committed:  committed,
+		LastOffset: last,
+		Lag:        max(lag, 0),
+	}
+}
+
+// RegisterLagMetrics 将消费组在 topics 上的积压注册为 OTEL 指标 kafka.consumer.lag，
+// 指标带有 group、topic、partition 三个属性，在每次采集时实时计算。
+// 使用全局 MeterProvider，返回的 Registration 可用于注销
+func RegisterLagMetrics(admin Admin, groupID string, topics ...string) (metric.Registration, error) {
+	meter := otel.Meter(instrumentationName)
+	gauge, err := meter.Int64ObservableGauge("kafka.consumer.lag",
+		metric.WithDescription("Number of messages the consumer group has not committed yet"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		var errs []error
+		for _, topic := range topics {
+			lags, err := admin.ConsumerLag(ctx, groupID, topic)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, lag := range lags {
+				o.ObserveInt64(gauge, lag.Lag, metric.WithAttributes(
+					attribute.String("group", groupID),
+					attribute.String("topic", lag.Topic),
+					attribute.String("partition", strconv.Itoa(lag.Partition)),
+				))
+			}
+		}
+		return errors.Join(errs...)
+	}, gauge)
+}
@@ -0,0 +1,81 @@
+package kafkax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMemoryBrokerAdmin(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+
+	assert.Nil(t, broker.EnsureTopics(ctx, TopicSpec{Topic: "orders", Partitions: 2}, TopicSpec{Topic: "audit"}))
+	// 重复调用不报错，分区不足时扩容
+	assert.Nil(t, broker.EnsureTopics(ctx, TopicSpec{Topic: "orders", Partitions: 3}))
+
+	topics, err := broker.ListTopics(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []TopicInfo{
+		{Topic: "audit", Partitions: []int{0}},
+		{Topic: "orders", Partitions: []int{0, 1, 2}},
+	}, topics)
+
+	_, err = broker.ConsumerLag(ctx, "billing", "missing")
+	assert.ErrorIs(t, err, kafka.UnknownTopicOrPartition)
+}
+
+func TestConsumerLag(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+	broker.CreateTopic("orders", 2)
+	assert.Nil(t, broker.Produce("orders",
+		kafka.Message{Partition: 0, Value: []byte("a")},
+		kafka.Message{Partition: 0, Value: []byte("b")},
+		kafka.Message{Partition: 0, Value: []byte("c")},
+		kafka.Message{Partition: 1, Value: []byte("d")},
+	))
+
+	consumer := broker.NewConsumer("billing", "orders")
+	defer consumer.Close()
+	msg, err := consumer.FetchMessage(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, consumer.CommitMessages(ctx, msg))
+
+	lags, err := broker.ConsumerLag(ctx, "billing", "orders")
+	assert.Nil(t, err)
+	assert.Equal(t, []PartitionLag{
+		{Topic: "orders", Partition: 0, Committed: 1, LastOffset: 3, Lag: 2},
+		{Topic: "orders", Partition: 1, Committed: -1, LastOffset: 1, Lag: 1},
+	}, lags)
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prev := otel.GetMeterProvider()
+	otel.SetMeterProvider(provider)
+	t.Cleanup(func() {
+		otel.SetMeterProvider(prev)
+		_ = provider.Shutdown(ctx)
+	})
+
+	reg, err := RegisterLagMetrics(broker, "billing", "orders")
+	assert.Nil(t, err)
+	defer reg.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	assert.Nil(t, reader.Collect(ctx, &rm))
+	assert.Len(t, rm.ScopeMetrics, 1)
+	gauge := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Gauge[int64])
+	got := map[string]int64{}
+	for _, dp := range gauge.DataPoints {
+		partition, _ := dp.Attributes.Value(attribute.Key("partition"))
+		got[partition.AsString()] = dp.Value
+	}
+	assert.Equal(t, map[string]int64{"0": 2, "1": 1}, got)
+}
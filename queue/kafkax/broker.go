@@ -0,0 +1,74 @@
+package kafkax
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+type (
+	// Conn 抽象 producer 写消息所用的分区连接，*kafka.Conn 实现了该接口
+	Conn interface {
+		SetWriteDeadline(t time.Time) error
+		WriteMessages(msgs ...kafka.Message) (int, error)
+		Close() error
+	}
+
+	// Consumer 抽象按消费组读取消息的能力，*kafka.Reader 实现了该接口
+	Consumer interface {
+		FetchMessage(ctx context.Context) (kafka.Message, error)
+		CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+		Close() error
+	}
+
+	// Broker 抽象 kafka 集群，生产环境使用 NewBroker，测试中可替换为 MemoryBroker
+	Broker interface {
+		// DialLeader 建立到 topic 指定分区 leader 的连接
+		DialLeader(ctx context.Context, topic string, partition int) (Conn, error)
+		// NewConsumer 创建属于 groupID 消费组的 topic 消费者
+		NewConsumer(groupID, topic string) Consumer
+	}
+)
+
+// kafkaBroker 基于 kafka-go 的真实集群实现
+type kafkaBroker struct {
+	config *KafkaConfig
+}
+
+// NewBroker 根据配置创建连接真实 kafka 集群的 Broker
+func NewBroker(c *KafkaConfig) Broker {
+	return &kafkaBroker{config: c}
+}
+
+// NewConsumer 使用配置中的 GroupID 创建 topic 消费者
+func NewConsumer(c *KafkaConfig, topic string) Consumer {
+	return NewBroker(c).NewConsumer(c.GroupID, topic)
+}
+
+func (b *kafkaBroker) dialer() *kafka.Dialer {
+	return &kafka.Dialer{
+		Timeout:   10 * time.Second,
+		DualStack: true,
+		SASLMechanism: plain.Mechanism{
+			Username: b.config.Username,
+			Password: b.config.Password,
+		},
+		KeepAlive: 10 * time.Second,
+	}
+}
+
+func (b *kafkaBroker) DialLeader(ctx context.Context, topic string, partition int) (Conn, error) {
+	return b.dialer().DialLeader(ctx, "tcp", b.config.Brokers, topic, partition)
+}
+
+func (b *kafkaBroker) NewConsumer(groupID, topic string) Consumer {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers: strings.Split(b.config.Brokers, ","),
+		GroupID: groupID,
+		Topic:   topic,
+		Dialer:  b.dialer(),
+	})
+}
@@ -0,0 +1,373 @@
+package kafkax
+
+import (
+	"context"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// MemoryBroker 是 Broker 的内存实现，用于在没有 kafka 集群的环境下测试业务代码。
+// 它按 topic/分区记录所有写入的消息，维护消费组的已提交位点，
+// 并支持向拨号和写入注入错误（如 kafka.LeaderNotAvailable、io.EOF）以覆盖重连逻辑。
+//
+// 注意：MemoryBroker 不做分区再均衡，同一消费组内的每个 Consumer
+// 都会从该组已提交的位点开始读取全部分区。
+type MemoryBroker struct {
+	mu        sync.Mutex
+	topics    map[string][][]kafka.Message        // topic -> 分区 -> 消息
+	groups    map[string]map[string]map[int]int64 // group -> topic -> 分区 -> 下一个待消费位点
+	dialErrs  map[string][]error
+	writeErrs map[string][]error
+	dials     map[string]int
+	notify    chan struct{} // 每次写入后关闭并替换，用于唤醒等待中的消费者
+}
+
+// NewMemoryBroker 创建内存 Broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:    make(map[string][][]kafka.Message),
+		groups:    make(map[string]map[string]map[int]int64),
+		dialErrs:  make(map[string][]error),
+		writeErrs: make(map[string][]error),
+		dials:     make(map[string]int),
+		notify:    make(chan struct{}),
+	}
+}
+
+// CreateTopic 创建拥有 partitions 个分区的 topic，topic 已存在时不做任何修改
+func (b *MemoryBroker) CreateTopic(topic string, partitions int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.createTopicLocked(topic, partitions)
+}
+
+func (b *MemoryBroker) createTopicLocked(topic string, partitions int) {
+	if _, ok := b.topics[topic]; ok {
+		return
+	}
+	if partitions <= 0 {
+		partitions = 1
+	}
+	b.topics[topic] = make([][]kafka.Message, partitions)
+}
+
+// InjectDialError 让 topic 接下来的 len(errs) 次拨号依次返回 errs 中的错误
+func (b *MemoryBroker) InjectDialError(topic string, errs ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dialErrs[topic] = append(b.dialErrs[topic], errs...)
+}
+
+// InjectWriteError 让 topic 接下来的 len(errs) 次写入依次返回 errs 中的错误
+func (b *MemoryBroker) InjectWriteError(topic string, errs ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writeErrs[topic] = append(b.writeErrs[topic], errs...)
+}
+
+// Dials 返回 topic 累计的拨号次数，包括注入错误导致失败的拨号
+func (b *MemoryBroker) Dials(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dials[topic]
+}
+
+// Messages 返回 topic 所有分区的消息，按分区、位点排序
+func (b *MemoryBroker) Messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var msgs []kafka.Message
+	for _, partition := range b.topics[topic] {
+		msgs = append(msgs, partition...)
+	}
+	return msgs
+}
+
+// PartitionMessages 返回 topic 指定分区的消息
+func (b *MemoryBroker) PartitionMessages(topic string, partition int) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	partitions := b.topics[topic]
+	if partition < 0 || partition >= len(partitions) {
+		return nil
+	}
+	return append([]kafka.Message(nil), partitions[partition]...)
+}
+
+// Produce 直接向 topic 写入消息，消息按 Partition 字段落入对应分区，用于准备测试数据
+func (b *MemoryBroker) Produce(topic string, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.createTopicLocked(topic, 1)
+	for _, msg := range msgs {
+		if msg.Partition < 0 || msg.Partition >= len(b.topics[topic]) {
+			return kafka.UnknownTopicOrPartition
+		}
+	}
+	for _, msg := range msgs {
+		b.appendLocked(topic, msg.Partition, msg)
+	}
+	b.broadcastLocked()
+	return nil
+}
+
+// CommittedOffset 返回消费组在 topic 分区上的已提交位点，未提交过时返回 -1
+func (b *MemoryBroker) CommittedOffset(groupID, topic string, partition int) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if offset, ok := b.groups[groupID][topic][partition]; ok {
+		return offset
+	}
+	return -1
+}
+
+// EnsureTopics 创建不存在的 topic，已存在的 topic 分区不足时扩容，副本数和配置被忽略
+func (b *MemoryBroker) EnsureTopics(ctx context.Context, specs ...TopicSpec) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, spec := range specs {
+		b.createTopicLocked(spec.Topic, spec.Partitions)
+		for len(b.topics[spec.Topic]) < spec.Partitions {
+			b.topics[spec.Topic] = append(b.topics[spec.Topic], nil)
+		}
+	}
+	return nil
+}
+
+// ListTopics 列出所有 topic 及分区，按 topic 名称排序
+func (b *MemoryBroker) ListTopics(ctx context.Context) ([]TopicInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	infos := make([]TopicInfo, 0, len(b.topics))
+	for topic, partitions := range b.topics {
+		info := TopicInfo{Topic: topic}
+		for p := range partitions {
+			info.Partitions = append(info.Partitions, p)
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Topic < infos[j].Topic })
+	return infos, nil
+}
+
+// ConsumerLag 计算消费组在 topic 每个分区上的积压
+func (b *MemoryBroker) ConsumerLag(ctx context.Context, groupID, topic string) ([]PartitionLag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	partitions, ok := b.topics[topic]
+	if !ok {
+		return nil, kafka.UnknownTopicOrPartition
+	}
+	lags := make([]PartitionLag, 0, len(partitions))
+	for p, msgs := range partitions {
+		committed, ok := b.groups[groupID][topic][p]
+		if !ok {
+			committed = -1
+		}
+		lags = append(lags, newPartitionLag(topic, p, committed, int64(len(msgs))))
+	}
+	return lags, nil
+}
+
+// DialLeader 返回写入 topic 指定分区的内存连接，topic 不存在时自动创建
+func (b *MemoryBroker) DialLeader(ctx context.Context, topic string, partition int) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dials[topic]++
+	if err := popError(b.dialErrs, topic); err != nil {
+		return nil, err
+	}
+	b.createTopicLocked(topic, 1)
+	if partition < 0 || partition >= len(b.topics[topic]) {
+		return nil, kafka.UnknownTopicOrPartition
+	}
+	return &memoryConn{broker: b, topic: topic, partition: partition}, nil
+}
+
+// NewConsumer 创建属于 groupID 消费组的内存消费者
+func (b *MemoryBroker) NewConsumer(groupID, topic string) Consumer {
+	return &memoryConsumer{
+		broker: b,
+		group:  groupID,
+		topic:  topic,
+		closed: make(chan struct{}),
+	}
+}
+
+func (b *MemoryBroker) appendLocked(topic string, partition int, msg kafka.Message) {
+	msg.Topic = topic
+	msg.Partition = partition
+	msg.Offset = int64(len(b.topics[topic][partition]))
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	b.topics[topic][partition] = append(b.topics[topic][partition], msg)
+}
+
+func (b *MemoryBroker) broadcastLocked() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+func (b *MemoryBroker) commitLocked(groupID string, msg kafka.Message) {
+	topics, ok := b.groups[groupID]
+	if !ok {
+		topics = make(map[string]map[int]int64)
+		b.groups[groupID] = topics
+	}
+	partitions, ok := topics[msg.Topic]
+	if !ok {
+		partitions = make(map[int]int64)
+		topics[msg.Topic] = partitions
+	}
+	if next := msg.Offset + 1; next > partitions[msg.Partition] {
+		partitions[msg.Partition] = next
+	}
+}
+
+func popError(errs map[string][]error, topic string) error {
+	queue := errs[topic]
+	if len(queue) == 0 {
+		return nil
+	}
+	errs[topic] = queue[1:]
+	return queue[0]
+}
+
+// memoryConn 是 MemoryBroker 的分区连接
+type memoryConn struct {
+	broker    *MemoryBroker
+	topic     string
+	partition int
+	closed    bool
+}
+
+func (c *memoryConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (c *memoryConn) WriteMessages(msgs ...kafka.Message) (int, error) {
+	b := c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	if err := popError(b.writeErrs, c.topic); err != nil {
+		return 0, err
+	}
+	var n int
+	for _, msg := range msgs {
+		b.appendLocked(c.topic, c.partition, msg)
+		n += len(msg.Key) + len(msg.Value)
+	}
+	b.broadcastLocked()
+	return n, nil
+}
+
+func (c *memoryConn) Close() error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// memoryConsumer 是 MemoryBroker 的消费者，按分区轮询读取消息
+type memoryConsumer struct {
+	broker    *MemoryBroker
+	group     string
+	topic     string
+	cursors   map[int]int64 // 分区 -> 下一个待读取位点
+	next      int           // 下一次优先读取的分区
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *memoryConsumer) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		select {
+		case <-c.closed:
+			return kafka.Message{}, io.EOF
+		default:
+		}
+
+		b := c.broker
+		b.mu.Lock()
+		msg, ok := c.pollLocked()
+		wait := b.notify
+		b.mu.Unlock()
+		if ok {
+			return msg, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-c.closed:
+			return kafka.Message{}, io.EOF
+		case <-wait:
+		}
+	}
+}
+
+func (c *memoryConsumer) pollLocked() (kafka.Message, bool) {
+	partitions := c.broker.topics[c.topic]
+	if c.cursors == nil {
+		c.cursors = make(map[int]int64)
+	}
+	for i := 0; i < len(partitions); i++ {
+		p := (c.next + i) % len(partitions)
+		offset, ok := c.cursors[p]
+		if !ok {
+			// 首次读取该分区时从消费组已提交位点开始
+			offset = c.broker.groups[c.group][c.topic][p]
+			c.cursors[p] = offset
+		}
+		if offset < int64(len(partitions[p])) {
+			c.cursors[p] = offset + 1
+			c.next = p + 1
+			return partitions[p][offset], true
+		}
+	}
+	return kafka.Message{}, false
+}
+
+func (c *memoryConsumer) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case <-c.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	b := c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, msg := range msgs {
+		b.commitLocked(c.group, msg)
+	}
+	return nil
+}
+
+func (c *memoryConsumer) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
@@ -0,0 +1,160 @@
+package kafkax
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBrokerPublish(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+
+	producer, err := NewProducer(ctx, broker, "orders")
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	err = producer.Publish(ctx, []kafka.Message{
+		{Key: []byte("1"), Value: []byte("a")},
+		{Key: []byte("2"), Value: []byte("b")},
+	})
+	assert.Nil(t, err)
+
+	msgs := broker.PartitionMessages("orders", 0)
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, "orders", msgs[1].Topic)
+	assert.Equal(t, int64(1), msgs[1].Offset)
+	assert.Equal(t, []byte("b"), msgs[1].Value)
+}
+
+func TestMemoryBrokerReconnect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("leader not available", func(t *testing.T) {
+		broker := NewMemoryBroker()
+		producer, err := NewProducer(ctx, broker, "orders")
+		assert.Nil(t, err)
+
+		broker.InjectWriteError("orders", kafka.LeaderNotAvailable)
+		err = producer.Publish(ctx, []kafka.Message{{Value: []byte("a")}})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, broker.Dials("orders"))
+		assert.Len(t, broker.Messages("orders"), 1)
+	})
+
+	t.Run("reconnect failed", func(t *testing.T) {
+		broker := NewMemoryBroker()
+		producer, err := NewProducer(ctx, broker, "orders")
+		assert.Nil(t, err)
+
+		broker.InjectWriteError("orders", io.EOF)
+		broker.InjectDialError("orders", kafka.LeaderNotAvailable)
+		err = producer.Publish(ctx, []kafka.Message{{Value: []byte("a")}})
+		assert.ErrorIs(t, err, kafka.LeaderNotAvailable)
+		assert.Empty(t, broker.Messages("orders"))
+	})
+
+	t.Run("not connection error", func(t *testing.T) {
+		broker := NewMemoryBroker()
+		producer, err := NewProducer(ctx, broker, "orders")
+		assert.Nil(t, err)
+
+		broker.InjectWriteError("orders", kafka.MessageSizeTooLarge)
+		err = producer.Publish(ctx, []kafka.Message{{Value: []byte("a")}})
+		assert.ErrorIs(t, err, kafka.MessageSizeTooLarge)
+		assert.Equal(t, 1, broker.Dials("orders"))
+	})
+}
+
+func TestMemoryBrokerConsumerGroup(t *testing.T) {
+	broker := NewMemoryBroker()
+	broker.CreateTopic("orders", 2)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := broker.Produce("orders",
+		kafka.Message{Partition: 0, Value: []byte("a")},
+		kafka.Message{Partition: 1, Value: []byte("b")},
+		kafka.Message{Partition: 0, Value: []byte("c")},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1), broker.CommittedOffset("billing", "orders", 0))
+
+	consumer := broker.NewConsumer("billing", "orders")
+	first, err := consumer.FetchMessage(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, consumer.CommitMessages(ctx, first))
+	assert.Equal(t, first.Offset+1, broker.CommittedOffset("billing", "orders", first.Partition))
+	assert.Nil(t, consumer.Close())
+
+	_, err = consumer.FetchMessage(ctx)
+	assert.ErrorIs(t, err, io.EOF)
+
+	// 同组的新消费者从已提交位点继续消费
+	consumer = broker.NewConsumer("billing", "orders")
+	defer consumer.Close()
+	var values []string
+	for i := 0; i < 2; i++ {
+		msg, err := consumer.FetchMessage(ctx)
+		assert.Nil(t, err)
+		values = append(values, string(msg.Value))
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, append(values, string(first.Value)))
+
+	// 其他消费组独立消费
+	other := broker.NewConsumer("audit", "orders")
+	defer other.Close()
+	msg, err := other.FetchMessage(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), msg.Offset)
+}
+
+func TestMemoryBrokerFetchWait(t *testing.T) {
+	broker := NewMemoryBroker()
+	consumer := broker.NewConsumer("billing", "orders")
+	defer consumer.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = broker.Produce("orders", kafka.Message{Value: []byte("late")})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := consumer.FetchMessage(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("late"), msg.Value)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = consumer.FetchMessage(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestProducerStats(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker()
+	producer, err := NewProducer(ctx, broker, "payments")
+	assert.Nil(t, err)
+
+	assert.Nil(t, producer.Publish(ctx, []kafka.Message{
+		{Key: []byte("1"), Value: []byte("abc")},
+		{Value: []byte("de")},
+	}))
+	broker.InjectWriteError("payments", io.EOF)
+	assert.Nil(t, producer.Publish(ctx, []kafka.Message{{Value: []byte("f")}}))
+	broker.InjectWriteError("payments", kafka.MessageSizeTooLarge)
+	assert.NotNil(t, producer.Publish(ctx, []kafka.Message{{Value: []byte("g")}}))
+
+	var stats ProducerStats
+	for _, s := range AllProducerStats() {
+		if s.Topic == "payments" {
+			stats = s
+		}
+	}
+	assert.Equal(t, ProducerStats{Topic: "payments", Messages: 3, Bytes: 7, Errors: 1, Reconnects: 1}, stats)
+}
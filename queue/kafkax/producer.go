@@ -3,7 +3,6 @@ package kafkax
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"net"
 	"sync"
@@ -11,10 +10,14 @@ import (
 	"time"
 
 	"github.com/segmentio/kafka-go"
-	"github.com/segmentio/kafka-go/sasl/plain"
+
+	"github.com/betacats/go-core/utils/logx"
 )
 
-var producerPool sync.Map // key: topic, value: *KafkaProducer
+var (
+	producerPool sync.Map // key: topic, value: *KafkaProducer
+	logger       = logx.Module("kafkax")
+)
 
 type KafkaConfig struct {
 	Username string
@@ -24,47 +27,40 @@ type KafkaConfig struct {
 }
 
 type KafkaProducer struct {
-	conn   *kafka.Conn
-	config *KafkaConfig
+	conn   Conn
+	broker Broker
 	topic  string
 }
 
 // InitProducerForTopics 初始化每个 topic 的 producer
 func InitProducerForTopics(ctx context.Context, c *KafkaConfig, topics []string) {
+	InitProducerForTopicsWithBroker(ctx, NewBroker(c), topics)
+}
+
+// InitProducerForTopicsWithBroker 使用指定 Broker 初始化每个 topic 的 producer，
+// 测试中可传入 MemoryBroker 以脱离真实集群
+func InitProducerForTopicsWithBroker(ctx context.Context, b Broker, topics []string) {
 	for _, topic := range topics {
-		producer := newKafkaProducerWithTopic(ctx, c, topic)
+		producer, err := NewProducer(ctx, b, topic)
+		if err != nil {
+			panic(err)
+		}
 		producerPool.LoadOrStore(topic, producer)
-		fmt.Println("Kafka producer initialized for topic:", topic)
+		logger.InfoContext(ctx, "producer initialized", "topic", topic)
 	}
 }
 
-// 创建带 topic 的 producer
-func newKafkaProducerWithTopic(ctx context.Context, c *KafkaConfig, topic string) *KafkaProducer {
-	mechanism := plain.Mechanism{
-		Username: c.Username,
-		Password: c.Password,
-	}
-	dialer := &kafka.Dialer{
-		Timeout:       10 * time.Second,
-		DualStack:     true,
-		SASLMechanism: mechanism,
-		KeepAlive:     10 * time.Second,
-	}
-	kConn, err := dialer.DialLeader(
-		ctx,
-		"tcp",
-		c.Brokers,
-		topic,
-		0,
-	)
+// NewProducer 创建 topic 的 producer，不放入全局连接池
+func NewProducer(ctx context.Context, b Broker, topic string) (*KafkaProducer, error) {
+	kConn, err := b.DialLeader(ctx, topic, 0)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	return &KafkaProducer{
 		conn:   kConn,
-		config: c,
+		broker: b,
 		topic:  topic,
-	}
+	}, nil
 }
 
 // GetProducerByTopic 获取指定 topic 的 producer
@@ -87,6 +83,16 @@ func isConnectionError(err error) bool {
 
 // Publish 发布消息，自动重连
 func (k *KafkaProducer) Publish(ctx context.Context, msg []kafka.Message) error {
+	err := k.publish(ctx, msg)
+	if err != nil {
+		countersFor(k.topic).errors.Add(1)
+		return err
+	}
+	countersFor(k.topic).written(msg)
+	return nil
+}
+
+func (k *KafkaProducer) publish(ctx context.Context, msg []kafka.Message) error {
 	var err error
 	if err = k.conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
 		return err
@@ -95,6 +101,7 @@ func (k *KafkaProducer) Publish(ctx context.Context, msg []kafka.Message) error
 	if err != nil {
 		// 扩展错误检查范围
 		if isConnectionError(err) {
+			logger.WarnContext(ctx, "connection lost, reconnecting", "topic", k.topic, "error", err)
 			// 尝试重连
 			if reconnectErr := k.reconnect(ctx); reconnectErr != nil {
 				return reconnectErr
@@ -115,30 +122,26 @@ func (k *KafkaProducer) reconnect(ctx context.Context) error {
 	if k.conn != nil {
 		_ = k.conn.Close()
 	}
-	mechanism := plain.Mechanism{
-		Username: k.config.Username,
-		Password: k.config.Password,
-	}
-	dialer := &kafka.Dialer{
-		Timeout:       10 * time.Second,
-		DualStack:     true,
-		SASLMechanism: mechanism,
-		KeepAlive:     10 * time.Second,
-	}
-	kConn, err := dialer.DialLeader(
-		ctx,
-		"tcp",
-		k.config.Brokers,
-		k.topic, 0)
+	countersFor(k.topic).reconnects.Add(1)
+	kConn, err := k.broker.DialLeader(ctx, k.topic, 0)
 	if err != nil {
 		return err
 	}
+	// 连接池中保存的是同一个指针，替换 conn 即可生效，
+	// 不再 Store 回连接池，避免 NewProducer 创建的独立 producer 覆盖池中实例
 	k.conn = kConn
-	// 将重连后的生产者放回连接池
-	producerPool.Store(k.topic, k)
 	return nil
 }
 
+// Ping 重新拨号 topic 的 leader 检查集群是否可用，不影响当前连接
+func (k *KafkaProducer) Ping(ctx context.Context) error {
+	conn, err := k.broker.DialLeader(ctx, k.topic, 0)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
 // Close 关闭连接
 func (k *KafkaProducer) Close() {
 	if k.conn != nil {
@@ -8,15 +8,11 @@ import (
 )
 
 func TestKafkaProducerPublish(t *testing.T) {
-	cfg := &KafkaConfig{
-		Username: "test",
-		Password: "test",
-		Brokers:  "localhost:9092",
-	}
+	broker := NewMemoryBroker()
 	topic := "test-topic"
 	ctx := context.Background()
 	// 先初始化 producer
-	InitProducerForTopics(ctx, cfg, []string{topic})
+	InitProducerForTopicsWithBroker(ctx, broker, []string{topic})
 
 	// 获取指定 topic 的 producer
 	producer, err := GetProducerByTopic(topic)
@@ -30,7 +26,8 @@ func TestKafkaProducerPublish(t *testing.T) {
 	err = producer.Publish(ctx, []kafka.Message{msg})
 	if err != nil {
 		t.Fatalf("消息发送失败: %v", err)
-	} else {
-		t.Log("消息发送成功")
+	}
+	if msgs := broker.Messages(topic); len(msgs) != 1 || string(msgs[0].Value) != "test message" {
+		t.Fatalf("broker 收到的消息不正确: %v", msgs)
 	}
 }
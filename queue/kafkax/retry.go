@@ -0,0 +1,319 @@
+package kafkax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// HeaderAttempt 记录消息已重试的次数，原始消息没有该 header
+	HeaderAttempt = "x-retry-attempt"
+	// HeaderOriginalTopic 记录消息最初所在的 topic
+	HeaderOriginalTopic = "x-original-topic"
+	// HeaderRetryAt 记录消息最早可被处理的时间，毫秒时间戳
+	HeaderRetryAt = "x-retry-at"
+	// HeaderError 记录最近一次处理失败的错误信息
+	HeaderError = "x-retry-error"
+)
+
+type (
+	// Handler 处理单条消息，返回错误时由 RetryRouter 决定重试或进入死信队列
+	Handler func(ctx context.Context, msg kafka.Message) error
+
+	// RetryTier 一级重试，失败的消息会被投递到 Topic，并在 Delay 之后再次处理
+	RetryTier struct {
+		Topic string
+		Delay time.Duration
+	}
+
+	// RetryPolicy 重试阶梯，消息依次经过 Tiers，全部失败后进入 DLQTopic
+	RetryPolicy struct {
+		Tiers    []RetryTier
+		DLQTopic string
+	}
+
+	// ReplayOptions 重放死信消息的选项
+	ReplayOptions struct {
+		Max         int                      // 最多重放条数，0 表示不限制
+		IdleTimeout time.Duration            // 超过该时间没有新消息即结束，默认 5s
+		Filter      func(kafka.Message) bool // 返回 false 的消息会被跳过并提交
+		DryRun      bool                     // 只统计不投递，也不提交位点
+		ParkTopic   string                   // 缺少原始 topic 的消息投递到该 topic，为空时记录日志后跳过，两种情况都会提交位点
+	}
+)
+
+// NewRetryPolicy 为 topic 生成默认重试阶梯：10s、1m、10m 三级重试和死信队列，
+// 对应 topic 为 <topic>.retry.10s、<topic>.retry.1m、<topic>.retry.10m 和 <topic>.dlq
+func NewRetryPolicy(topic string) RetryPolicy {
+	return RetryPolicy{
+		Tiers: []RetryTier{
+			{Topic: topic + ".retry.10s", Delay: 10 * time.Second},
+			{Topic: topic + ".retry.1m", Delay: time.Minute},
+			{Topic: topic + ".retry.10m", Delay: 10 * time.Minute},
+		},
+		DLQTopic: topic + ".dlq",
+	}
+}
+
+// RetryRouter 将处理失败的消息按 RetryPolicy 投递到重试 topic 或死信队列
+type RetryRouter struct {
+	broker    Broker
+	policy    RetryPolicy
+	mu        sync.Mutex
+	producers map[string]*KafkaProducer
+}
+
+// NewRetryRouter 创建重试路由
+func NewRetryRouter(b Broker, policy RetryPolicy) *RetryRouter {
+	return &RetryRouter{
+		broker:    b,
+		policy:    policy,
+		producers: make(map[string]*KafkaProducer),
+	}
+}
+
+// Route 将处理失败的消息投递到下一级重试 topic，重试次数耗尽后投递到死信队列
+func (r *RetryRouter) Route(ctx context.Context, msg kafka.Message, cause error) error {
+	attempt := Attempt(msg)
+	out := kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: withoutRetryHeaders(msg.Headers),
+	}
+	out.Headers = append(out.Headers,
+		kafka.Header{Key: HeaderAttempt, Value: []byte(strconv.Itoa(attempt + 1))},
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(OriginalTopic(msg))},
+	)
+	if cause != nil {
+		out.Headers = append(out.Headers, kafka.Header{Key: HeaderError, Value: []byte(cause.Error())})
+	}
+
+	topic := r.policy.DLQTopic
+	if attempt < len(r.policy.Tiers) {
+		tier := r.policy.Tiers[attempt]
+		topic = tier.Topic
+		retryAt := time.Now().Add(tier.Delay).UnixMilli()
+		out.Headers = append(out.Headers, kafka.Header{Key: HeaderRetryAt, Value: []byte(strconv.FormatInt(retryAt, 10))})
+	}
+	if topic == "" {
+		return fmt.Errorf("kafkax: retry exhausted and no dlq topic configured: %w", cause)
+	}
+	return r.publish(ctx, topic, out)
+}
+
+// Consume 持续从 c 读取消息并交给 h 处理，适用于原始 topic 和各级重试 topic。
+// 带有 HeaderRetryAt 的消息会等待到期后再处理；处理失败的消息交给 Route，
+// 成功或路由完成后提交位点。c 被关闭时返回 nil，ctx 取消时返回 ctx.Err()
+func (r *RetryRouter) Consume(ctx context.Context, c Consumer, h Handler) error {
+	for {
+		msg, err := c.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if err = waitUntil(ctx, RetryAt(msg)); err != nil {
+			return err
+		}
+
+		if handleErr := h(ctx, msg); handleErr != nil {
+			// 路由失败时不提交位点，交由上层决定是否重启消费
+			if err = r.Route(ctx, msg, handleErr); err != nil {
+				return err
+			}
+		}
+
+		if err = c.CommitMessages(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// ReplayDLQ 从死信队列消费者 c 中读取消息并投递回原始 topic，重试计数被清零。
+// 在 opts.IdleTimeout 内没有新消息、达到 opts.Max 或 ctx 取消时结束，返回重放的条数，
+// 便于在命令行工具中一次性执行
+func (r *RetryRouter) ReplayDLQ(ctx context.Context, c Consumer, opts ReplayOptions) (int, error) {
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 5 * time.Second
+	}
+
+	var replayed int
+	for opts.Max <= 0 || replayed < opts.Max {
+		fetchCtx, cancel := context.WithTimeout(ctx, opts.IdleTimeout)
+		msg, err := c.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if (ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded)) || errors.Is(err, io.EOF) {
+				return replayed, nil
+			}
+			return replayed, err
+		}
+
+		switch {
+		case opts.Filter != nil && !opts.Filter(msg):
+		case !hasHeader(msg, HeaderOriginalTopic):
+			// 无法重放的消息转存或跳过，不阻塞后续消息
+			if err = r.park(ctx, msg, opts); err != nil {
+				return replayed, err
+			}
+		default:
+			if !opts.DryRun {
+				err = r.publish(ctx, OriginalTopic(msg), kafka.Message{
+					Key:     msg.Key,
+					Value:   msg.Value,
+					Headers: withoutRetryHeaders(msg.Headers),
+				})
+				if err != nil {
+					return replayed, err
+				}
+			}
+			replayed++
+		}
+
+		if !opts.DryRun {
+			if err = c.CommitMessages(ctx, msg); err != nil {
+				return replayed, err
+			}
+		}
+	}
+	return replayed, nil
+}
+
+// Close 关闭重试路由创建的所有 producer
+func (r *RetryRouter) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for topic, p := range r.producers {
+		p.Close()
+		delete(r.producers, topic)
+	}
+}
+
+// park 将缺少原始 topic 的消息投递到 opts.ParkTopic，未配置时只记录日志
+func (r *RetryRouter) park(ctx context.Context, msg kafka.Message, opts ReplayOptions) error {
+	if opts.ParkTopic == "" {
+		logger.WarnContext(ctx, "skip dlq message without original topic",
+			"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+		return nil
+	}
+	if opts.DryRun {
+		return nil
+	}
+	return r.publish(ctx, opts.ParkTopic, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: msg.Headers})
+}
+
+// publish 只在取得 producer 时持有锁，写入在锁外进行，慢的 topic 不阻塞其他 topic
+func (r *RetryRouter) publish(ctx context.Context, topic string, msg kafka.Message) error {
+	p, err := r.producer(ctx, topic)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, []kafka.Message{msg})
+}
+
+func (r *RetryRouter) producer(ctx context.Context, topic string) (*KafkaProducer, error) {
+	r.mu.Lock()
+	p, ok := r.producers[topic]
+	r.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+
+	p, err := NewProducer(ctx, r.broker, topic)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 并发创建时保留先写入的 producer
+	if exist, ok := r.producers[topic]; ok {
+		p.Close()
+		return exist, nil
+	}
+	r.producers[topic] = p
+	return p, nil
+}
+
+// Attempt 返回消息已重试的次数，原始消息为 0
+func Attempt(msg kafka.Message) int {
+	v, ok := headerValue(msg, HeaderAttempt)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// OriginalTopic 返回消息最初所在的 topic，原始消息返回其自身的 topic
+func OriginalTopic(msg kafka.Message) string {
+	if v, ok := headerValue(msg, HeaderOriginalTopic); ok {
+		return v
+	}
+	return msg.Topic
+}
+
+// RetryAt 返回消息最早可被处理的时间，没有 HeaderRetryAt 时返回零值
+func RetryAt(msg kafka.Message) time.Time {
+	v, ok := headerValue(msg, HeaderRetryAt)
+	if !ok {
+		return time.Time{}
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func waitUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func headerValue(msg kafka.Message, key string) (string, bool) {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+func hasHeader(msg kafka.Message, key string) bool {
+	_, ok := headerValue(msg, key)
+	return ok
+}
+
+func withoutRetryHeaders(headers []kafka.Header) []kafka.Header {
+	var res []kafka.Header
+	for _, h := range headers {
+		switch h.Key {
+		case HeaderAttempt, HeaderOriginalTopic, HeaderRetryAt, HeaderError:
+			continue
+		}
+		res = append(res, h)
+	}
+	return res
+}
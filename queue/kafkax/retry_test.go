@@ -0,0 +1,172 @@
+package kafkax
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Tiers: []RetryTier{
+			{Topic: "orders.retry.1", Delay: 20 * time.Millisecond},
+			{Topic: "orders.retry.2", Delay: 40 * time.Millisecond},
+		},
+		DLQTopic: "orders.dlq",
+	}
+}
+
+func TestRetryRouterRoute(t *testing.T) {
+	broker := NewMemoryBroker()
+	router := NewRetryRouter(broker, testRetryPolicy())
+	defer router.Close()
+	ctx := context.Background()
+	cause := errors.New("downstream unavailable")
+
+	msg := kafka.Message{Topic: "orders", Value: []byte("a"), Headers: []kafka.Header{{Key: "trace", Value: []byte("t1")}}}
+	assert.Nil(t, router.Route(ctx, msg, cause))
+
+	retried := broker.Messages("orders.retry.1")
+	assert.Len(t, retried, 1)
+	assert.Equal(t, 1, Attempt(retried[0]))
+	assert.Equal(t, "orders", OriginalTopic(retried[0]))
+	assert.True(t, RetryAt(retried[0]).After(time.Now()))
+	v, _ := headerValue(retried[0], HeaderError)
+	assert.Equal(t, cause.Error(), v)
+	v, _ = headerValue(retried[0], "trace")
+	assert.Equal(t, "t1", v)
+
+	assert.Nil(t, router.Route(ctx, retried[0], cause))
+	retried = broker.Messages("orders.retry.2")
+	assert.Len(t, retried, 1)
+	assert.Equal(t, 2, Attempt(retried[0]))
+
+	assert.Nil(t, router.Route(ctx, retried[0], cause))
+	dead := broker.Messages("orders.dlq")
+	assert.Len(t, dead, 1)
+	assert.Equal(t, 3, Attempt(dead[0]))
+	assert.Equal(t, "orders", OriginalTopic(dead[0]))
+	assert.False(t, hasHeader(dead[0], HeaderRetryAt))
+}
+
+func TestRetryRouterConsume(t *testing.T) {
+	broker := NewMemoryBroker()
+	policy := testRetryPolicy()
+	router := NewRetryRouter(broker, policy)
+	defer router.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	assert.Nil(t, broker.Produce("orders", kafka.Message{Value: []byte("a")}))
+
+	var (
+		mu    sync.Mutex
+		calls []time.Time
+	)
+	done := make(chan struct{})
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, time.Now())
+		if Attempt(msg) < 2 {
+			return errors.New("downstream unavailable")
+		}
+		close(done)
+		return nil
+	}
+
+	consumers := []Consumer{broker.NewConsumer("billing", "orders")}
+	for _, tier := range policy.Tiers {
+		consumers = append(consumers, broker.NewConsumer("billing", tier.Topic))
+	}
+	errs := make(chan error, len(consumers))
+	for _, c := range consumers {
+		go func(c Consumer) {
+			errs <- router.Consume(ctx, c, handler)
+		}(c)
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("message was not retried")
+	}
+	for _, c := range consumers {
+		assert.Nil(t, c.Close())
+	}
+	for range consumers {
+		assert.Nil(t, <-errs)
+	}
+
+	assert.Len(t, calls, 3)
+	assert.GreaterOrEqual(t, calls[1].Sub(calls[0]), 15*time.Millisecond)
+	assert.GreaterOrEqual(t, calls[2].Sub(calls[1]), 35*time.Millisecond)
+	assert.Empty(t, broker.Messages("orders.dlq"))
+	assert.Equal(t, int64(1), broker.CommittedOffset("billing", "orders", 0))
+}
+
+func TestRetryRouterReplayDLQ(t *testing.T) {
+	broker := NewMemoryBroker()
+	router := NewRetryRouter(broker, testRetryPolicy())
+	defer router.Close()
+	ctx := context.Background()
+
+	for _, v := range []string{"a", "b", "c"} {
+		msg := kafka.Message{Topic: "orders", Value: []byte(v)}
+		msg.Headers = []kafka.Header{{Key: HeaderAttempt, Value: []byte("3")}}
+		assert.Nil(t, router.Route(ctx, msg, errors.New("boom")))
+	}
+	assert.Len(t, broker.Messages("orders.dlq"), 3)
+
+	consumer := broker.NewConsumer("replay", "orders.dlq")
+	defer consumer.Close()
+	n, err := router.ReplayDLQ(ctx, consumer, ReplayOptions{
+		IdleTimeout: 50 * time.Millisecond,
+		Filter:      func(msg kafka.Message) bool { return string(msg.Value) != "b" },
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	replayed := broker.Messages("orders")
+	assert.Len(t, replayed, 2)
+	assert.Equal(t, 0, Attempt(replayed[0]))
+	assert.False(t, hasHeader(replayed[0], HeaderError))
+	assert.Equal(t, int64(3), broker.CommittedOffset("replay", "orders.dlq", 0))
+}
+
+func TestRetryRouterReplayDLQWithoutOriginalTopic(t *testing.T) {
+	ctx := context.Background()
+	for _, park := range []string{"", "orders.parked"} {
+		t.Run("park="+park, func(t *testing.T) {
+			broker := NewMemoryBroker()
+			router := NewRetryRouter(broker, testRetryPolicy())
+			defer router.Close()
+
+			producer, err := NewProducer(ctx, broker, "orders.dlq")
+			assert.Nil(t, err)
+			defer producer.Close()
+			assert.Nil(t, producer.Publish(ctx, []kafka.Message{{Value: []byte("raw")}}))
+			msg := kafka.Message{Topic: "orders", Value: []byte("a")}
+			msg.Headers = []kafka.Header{{Key: HeaderAttempt, Value: []byte("3")}}
+			assert.Nil(t, router.Route(ctx, msg, errors.New("boom")))
+
+			consumer := broker.NewConsumer("replay", "orders.dlq")
+			defer consumer.Close()
+			n, err := router.ReplayDLQ(ctx, consumer, ReplayOptions{IdleTimeout: 50 * time.Millisecond, ParkTopic: park})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, n)
+			assert.Len(t, broker.Messages("orders"), 1)
+			assert.Equal(t, int64(2), broker.CommittedOffset("replay", "orders.dlq", 0))
+			if park != "" {
+				parked := broker.Messages(park)
+				assert.Len(t, parked, 1)
+				assert.Equal(t, "raw", string(parked[0].Value))
+			}
+		})
+	}
+}
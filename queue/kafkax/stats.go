@@ -0,0 +1,63 @@
+package kafkax
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ProducerStats 某个 topic 所有 producer 的累计发送统计
+type ProducerStats struct {
+	Topic      string
+	Messages   int64 // 发送成功的消息数
+	Bytes      int64 // 发送成功的消息 key 和 value 字节数
+	Errors     int64 // 发送失败的次数
+	Reconnects int64 // 重连次数
+}
+
+type producerCounters struct {
+	messages   atomic.Int64
+	bytes      atomic.Int64
+	errors     atomic.Int64
+	reconnects atomic.Int64
+}
+
+// producerStats key: topic, value: *producerCounters，包含池内和 NewProducer 创建的 producer
+var producerStats sync.Map
+
+func countersFor(topic string) *producerCounters {
+	if v, ok := producerStats.Load(topic); ok {
+		return v.(*producerCounters)
+	}
+	v, _ := producerStats.LoadOrStore(topic, &producerCounters{})
+	return v.(*producerCounters)
+}
+
+func (c *producerCounters) written(msgs []kafka.Message) {
+	var n int64
+	for _, msg := range msgs {
+		n += int64(len(msg.Key) + len(msg.Value))
+	}
+	c.messages.Add(int64(len(msgs)))
+	c.bytes.Add(n)
+}
+
+// AllProducerStats 返回每个 topic 的累计发送统计，按 topic 排序
+func AllProducerStats() []ProducerStats {
+	var res []ProducerStats
+	producerStats.Range(func(key, value any) bool {
+		c := value.(*producerCounters)
+		res = append(res, ProducerStats{
+			Topic:      key.(string),
+			Messages:   c.messages.Load(),
+			Bytes:      c.bytes.Load(),
+			Errors:     c.errors.Load(),
+			Reconnects: c.reconnects.Load(),
+		})
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].Topic < res[j].Topic })
+	return res
+}
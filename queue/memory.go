@@ -0,0 +1,178 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultMemory 为 DriverMemory 配置共享的进程内实例，使同一进程内的发布者和订阅者互通
+var defaultMemory = NewMemory()
+
+// Memory 是进程内的内存队列，同时实现 Publisher 和 Subscriber，适合本地开发和测试。
+// 同一 topic 的所有订阅者竞争消费（不区分消费组），Nack 的消息会重新放回队尾
+type Memory struct {
+	mu     sync.Mutex
+	topics map[string]*memoryTopic
+	seq    int64
+	closed chan struct{}
+	once   sync.Once
+}
+
+type memoryTopic struct {
+	pending []*Message
+	notify  chan struct{} // 有新消息时关闭并替换，用于唤醒订阅者
+}
+
+// NewMemory 创建独立的内存队列
+func NewMemory() *Memory {
+	return &Memory{
+		topics: make(map[string]*memoryTopic),
+		closed: make(chan struct{}),
+	}
+}
+
+func (m *Memory) topicLocked(topic string) *memoryTopic {
+	t, ok := m.topics[topic]
+	if !ok {
+		t = &memoryTopic{notify: make(chan struct{})}
+		m.topics[topic] = t
+	}
+	return t
+}
+
+func (m *Memory) isClosed() bool {
+	select {
+	case <-m.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Publish 将消息追加到 topic 队列
+func (m *Memory) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.isClosed() {
+		return ErrClosed
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.topicLocked(topic)
+	now := time.Now()
+	for _, msg := range msgs {
+		m.seq++
+		t.pending = append(t.pending, &Message{
+			ID:        strconv.FormatInt(m.seq, 10),
+			Topic:     topic,
+			Key:       msg.Key,
+			Payload:   msg.Payload,
+			Metadata:  copyMetadata(msg.Metadata),
+			Timestamp: now,
+		})
+	}
+	m.wakeLocked(t)
+	return nil
+}
+
+// Subscribe 订阅 topic
+func (m *Memory) Subscribe(ctx context.Context, topic string) (<-chan *Message, error) {
+	if m.isClosed() {
+		return nil, ErrClosed
+	}
+
+	out := make(chan *Message)
+	go func() {
+		defer close(out)
+		for {
+			stored, ok := m.next(ctx, topic)
+			if !ok {
+				return
+			}
+
+			msg := m.deliver(topic, stored)
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				m.requeue(topic, stored)
+				return
+			case <-m.closed:
+				m.requeue(topic, stored)
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// next 阻塞直到 topic 有可消费的消息，ctx 取消或队列关闭时返回 false
+func (m *Memory) next(ctx context.Context, topic string) (*Message, bool) {
+	for {
+		m.mu.Lock()
+		t := m.topicLocked(topic)
+		if len(t.pending) > 0 {
+			msg := t.pending[0]
+			t.pending = t.pending[1:]
+			m.mu.Unlock()
+			return msg, true
+		}
+		wait := t.notify
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-m.closed:
+			return nil, false
+		case <-wait:
+		}
+	}
+}
+
+func (m *Memory) deliver(topic string, stored *Message) *Message {
+	msg := &Message{
+		ID:        stored.ID,
+		Topic:     stored.Topic,
+		Key:       stored.Key,
+		Payload:   stored.Payload,
+		Metadata:  copyMetadata(stored.Metadata),
+		Timestamp: stored.Timestamp,
+	}
+	msg.ack = func(context.Context) error { return nil }
+	msg.nack = func(context.Context) error {
+		m.requeue(topic, stored)
+		return nil
+	}
+	return msg
+}
+
+func (m *Memory) requeue(topic string, msg *Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.topicLocked(topic)
+	t.pending = append(t.pending, msg)
+	m.wakeLocked(t)
+}
+
+func (m *Memory) wakeLocked(t *memoryTopic) {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+// Close 关闭内存队列，所有订阅 channel 随之关闭
+func (m *Memory) Close() error {
+	m.once.Do(func() { close(m.closed) })
+	return nil
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	res := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed 发布者或订阅者已关闭
+var ErrClosed = errors.New("queue: closed")
+
+type (
+	// Message 是在各后端之间通用的消息
+	Message struct {
+		ID        string            // 后端分配的消息标识，发布时可为空
+		Topic     string            // 消息所在 topic，订阅时由后端填充
+		Key       []byte            // 分区键，kafka 用于选择分区，其他后端仅透传
+		Payload   []byte            // 消息体
+		Metadata  map[string]string // 元数据，kafka 映射为 header，redis 映射为 stream 字段
+		Timestamp time.Time         // 消息写入时间，订阅时由后端填充
+
+		once sync.Once
+		ack  func(ctx context.Context) error
+		nack func(ctx context.Context) error
+	}
+
+	// Publisher 发布消息
+	Publisher interface {
+		Publish(ctx context.Context, topic string, msgs ...*Message) error
+		Close() error
+	}
+
+	// Subscriber 订阅 topic，返回的 channel 在 ctx 取消或 Subscriber 关闭后被关闭。
+	// 收到的每条消息都必须调用 Ack 或 Nack
+	Subscriber interface {
+		Subscribe(ctx context.Context, topic string) (<-chan *Message, error)
+		Close() error
+	}
+
+	// Handler 处理单条消息，返回 nil 时消息被 Ack，否则被 Nack
+	Handler func(ctx context.Context, msg *Message) error
+
+	// Middleware 包装 Handler，用于实现去重、日志、指标等通用逻辑
+	Middleware func(Handler) Handler
+)
+
+// NewMessage 创建待发布的消息
+func NewMessage(payload []byte) *Message {
+	return &Message{Payload: payload, Metadata: make(map[string]string)}
+}
+
+// Ack 确认消息已处理，后端不会再次投递。对同一条消息只有第一次 Ack/Nack 生效
+func (m *Message) Ack(ctx context.Context) error {
+	return m.settle(ctx, m.ack)
+}
+
+// Nack 表示消息处理失败，具体的重新投递策略由后端决定。对同一条消息只有第一次 Ack/Nack 生效
+func (m *Message) Nack(ctx context.Context) error {
+	return m.settle(ctx, m.nack)
+}
+
+func (m *Message) settle(ctx context.Context, f func(ctx context.Context) error) error {
+	var err error
+	m.once.Do(func() {
+		if f != nil {
+			err = f(ctx)
+		}
+	})
+	return err
+}
+
+// Get 读取元数据，不存在时返回空字符串
+func (m *Message) Get(key string) string {
+	return m.Metadata[key]
+}
+
+// Set 设置元数据
+func (m *Message) Set(key, value string) {
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]string)
+	}
+	m.Metadata[key] = value
+}
+
+// Chain 将多个中间件按顺序包装到 h 上，第一个中间件位于最外层
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Consume 订阅 topic 并逐条交给 h 处理，处理成功 Ack，失败 Nack。
+// ctx 取消或订阅结束时返回，Ack/Nack 失败时立即返回该错误
+func Consume(ctx context.Context, s Subscriber, topic string, h Handler, mws ...Middleware) error {
+	msgs, err := s.Subscribe(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	h = Chain(h, mws...)
+	for msg := range msgs {
+		if err = h(ctx, msg); err != nil {
+			err = msg.Nack(ctx)
+		} else {
+			err = msg.Ack(ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
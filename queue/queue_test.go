@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg := NewMessage([]byte("hello"))
+	msg.Set("trace", "t1")
+	assert.Nil(t, m.Publish(ctx, "orders", msg))
+
+	msgs, err := m.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+
+	got := <-msgs
+	assert.Equal(t, "orders", got.Topic)
+	assert.Equal(t, []byte("hello"), got.Payload)
+	assert.Equal(t, "t1", got.Get("trace"))
+	assert.False(t, got.Timestamp.IsZero())
+
+	// Nack 后重新投递，重复 Ack/Nack 不生效
+	assert.Nil(t, got.Nack(ctx))
+	assert.Nil(t, got.Nack(ctx))
+	redelivered := <-msgs
+	assert.Equal(t, got.ID, redelivered.ID)
+	assert.Nil(t, redelivered.Ack(ctx))
+
+	select {
+	case extra := <-msgs:
+		t.Fatalf("unexpected message %s", extra.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemoryCloseRequeue(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	pending := func() int {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.topicLocked("orders").pending)
+	}
+
+	// 订阅者已取出但还未被接收的消息在关闭时放回队列
+	_, err := m.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	assert.Nil(t, m.Publish(ctx, "orders", NewMessage([]byte("hello"))))
+	assert.Eventually(t, func() bool { return pending() == 0 }, time.Second, time.Millisecond)
+	assert.Nil(t, m.Close())
+	assert.Eventually(t, func() bool { return pending() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestConsume(t *testing.T) {
+	m := NewMemory()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, v := range []string{"a", "b", "c"} {
+		assert.Nil(t, m.Publish(ctx, "orders", NewMessage([]byte(v))))
+	}
+
+	var (
+		order    []string
+		attempts = map[string]int{}
+	)
+	logging := func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			order = append(order, "before:"+string(msg.Payload))
+			return next(ctx, msg)
+		}
+	}
+	err := Consume(ctx, m, "orders", func(ctx context.Context, msg *Message) error {
+		v := string(msg.Payload)
+		attempts[v]++
+		if v == "b" && attempts[v] == 1 {
+			return errors.New("retry later")
+		}
+		if len(attempts) == 3 && attempts["b"] == 2 {
+			cancel()
+		}
+		return nil
+	}, logging)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 1}, attempts)
+	assert.Equal(t, []string{"before:a", "before:b", "before:c", "before:b"}, order)
+}
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+
+	pub, sub, err := New(ctx, Config{Driver: DriverMemory})
+	assert.Nil(t, err)
+	// 关闭共享内存队列的包装不影响其他使用方
+	assert.Nil(t, pub.Close())
+	assert.Nil(t, sub.Close())
+	assert.Nil(t, pub.Publish(ctx, "new-test", NewMessage([]byte("a"))))
+
+	_, _, err = New(ctx, Config{Driver: DriverKafka})
+	assert.NotNil(t, err)
+	_, _, err = New(ctx, Config{Driver: "nsq"})
+	assert.NotNil(t, err)
+}
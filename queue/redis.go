@@ -0,0 +1,261 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rd "github.com/redis/go-redis/v9"
+
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+const (
+	redisFieldID       = "id"
+	redisFieldKey      = "key"
+	redisFieldPayload  = "payload"
+	redisFieldMetadata = "metadata"
+
+	// claimCount 每次认领或读取的最大消息数
+	claimCount = 10
+)
+
+// ownedClient 由 New 创建的 redis 客户端，发布者和订阅者都关闭后才关闭
+type ownedClient struct {
+	client rd.UniversalClient
+	refs   atomic.Int32
+}
+
+func newOwnedClient(client rd.UniversalClient, refs int32) *ownedClient {
+	c := &ownedClient{client: client}
+	c.refs.Store(refs)
+	return c
+}
+
+func (c *ownedClient) release() error {
+	if c == nil || c.refs.Add(-1) != 0 {
+		return nil
+	}
+	return c.client.Close()
+}
+
+// RedisPublisher 基于 redis stream 的发布者，每个 topic 对应一个 stream
+type RedisPublisher struct {
+	client rd.UniversalClient
+	maxLen int64
+	owned  *ownedClient
+	once   sync.Once
+}
+
+// NewRedisPublisher 创建 redis stream 发布者，maxLen 大于 0 时按近似长度裁剪 stream
+func NewRedisPublisher(client rd.UniversalClient, maxLen int64) *RedisPublisher {
+	return &RedisPublisher{client: client, maxLen: maxLen}
+}
+
+// Publish 使用 XADD 将消息写入 topic 对应的 stream
+func (p *RedisPublisher) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	for _, msg := range msgs {
+		metadata, err := jsonx.MarshalToString(msg.Metadata)
+		if err != nil {
+			return err
+		}
+		args := &rd.XAddArgs{
+			Stream: topic,
+			Values: map[string]any{
+				redisFieldID:       msg.ID,
+				redisFieldKey:      msg.Key,
+				redisFieldPayload:  msg.Payload,
+				redisFieldMetadata: metadata,
+			},
+		}
+		if p.maxLen > 0 {
+			args.MaxLen = p.maxLen
+			args.Approx = true
+		}
+		if err = p.client.XAdd(ctx, args).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 不关闭外部传入的 redis 客户端，New 创建的客户端在发布者和订阅者都关闭后关闭
+func (p *RedisPublisher) Close() error {
+	var err error
+	p.once.Do(func() { err = p.owned.release() })
+	return err
+}
+
+// RedisSubscriber 基于 redis stream 消费组的订阅者。
+// Ack 执行 XACK；Nack 的消息保留在 pending 列表中，空闲超过 claimIdle 后被重新认领投递
+type RedisSubscriber struct {
+	client    rd.UniversalClient
+	group     string
+	consumer  string
+	claimIdle time.Duration
+	owned     *ownedClient
+	closed    chan struct{}
+	once      sync.Once
+}
+
+// NewRedisSubscriber 创建属于 group 消费组的 redis stream 订阅者，claimIdle 默认 30s
+func NewRedisSubscriber(client rd.UniversalClient, group string, claimIdle time.Duration) *RedisSubscriber {
+	if claimIdle <= 0 {
+		claimIdle = 30 * time.Second
+	}
+	host, _ := os.Hostname()
+	return &RedisSubscriber{
+		client:    client,
+		group:     group,
+		consumer:  fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		claimIdle: claimIdle,
+		closed:    make(chan struct{}),
+	}
+}
+
+// Subscribe 订阅 topic 对应的 stream，消费组不存在时自动创建
+func (s *RedisSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *Message, error) {
+	select {
+	case <-s.closed:
+		return nil, ErrClosed
+	default:
+	}
+
+	err := s.client.XGroupCreateMkStream(ctx, topic, s.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-s.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	out := make(chan *Message)
+	go func() {
+		defer close(out)
+		defer cancel()
+		cursor := "0"
+		for ctx.Err() == nil {
+			msgs, err := s.read(ctx, topic, &cursor)
+			if err != nil {
+				if ctx.Err() == nil && sleep(ctx, time.Second) == nil {
+					continue
+				}
+				return
+			}
+			for _, xmsg := range msgs {
+				select {
+				case out <- s.deliver(topic, xmsg):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// read 先认领空闲过久的 pending 消息，没有时再阻塞读取新消息。
+// cursor 为 XAUTOCLAIM 的扫描位置，跨调用保留，扫描完整个 pending 列表后从 0 重新开始
+func (s *RedisSubscriber) read(ctx context.Context, topic string, cursor *string) ([]rd.XMessage, error) {
+	claimed, next, err := s.client.XAutoClaim(ctx, &rd.XAutoClaimArgs{
+		Stream:   topic,
+		Group:    s.group,
+		Consumer: s.consumer,
+		MinIdle:  s.claimIdle,
+		Start:    *cursor,
+		Count:    claimCount,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if next == "0-0" || next == "" {
+		next = "0"
+	}
+	*cursor = next
+	// 还有未扫描完的 pending 消息时先不读取新消息，下次继续认领
+	if len(claimed) > 0 || next != "0" {
+		return claimed, nil
+	}
+
+	streams, err := s.client.XReadGroup(ctx, &rd.XReadGroupArgs{
+		Group:    s.group,
+		Consumer: s.consumer,
+		Streams:  []string{topic, ">"},
+		Count:    claimCount,
+		Block:    2 * time.Second,
+	}).Result()
+	if errors.Is(err, rd.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var msgs []rd.XMessage
+	for _, stream := range streams {
+		msgs = append(msgs, stream.Messages...)
+	}
+	return msgs, nil
+}
+
+func (s *RedisSubscriber) deliver(topic string, xmsg rd.XMessage) *Message {
+	msg := &Message{
+		ID:       xmsg.ID,
+		Topic:    topic,
+		Key:      []byte(redisString(xmsg.Values[redisFieldKey])),
+		Payload:  []byte(redisString(xmsg.Values[redisFieldPayload])),
+		Metadata: make(map[string]string),
+	}
+	if id := redisString(xmsg.Values[redisFieldID]); id != "" {
+		msg.ID = id
+	}
+	if metadata := redisString(xmsg.Values[redisFieldMetadata]); metadata != "" {
+		_ = jsonx.UnmarshalFromString(metadata, &msg.Metadata)
+	}
+	// stream ID 的前半部分是写入时的毫秒时间戳
+	if ms, err := strconv.ParseInt(strings.SplitN(xmsg.ID, "-", 2)[0], 10, 64); err == nil {
+		msg.Timestamp = time.UnixMilli(ms)
+	}
+
+	msg.ack = func(ctx context.Context) error {
+		return s.client.XAck(ctx, topic, s.group, xmsg.ID).Err()
+	}
+	msg.nack = func(context.Context) error {
+		return nil
+	}
+	return msg
+}
+
+// Close 停止所有订阅，不关闭外部传入的 redis 客户端，New 创建的客户端在发布者和订阅者都关闭后关闭
+func (s *RedisSubscriber) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.closed)
+		err = s.owned.release()
+	})
+	return err
+}
+
+func redisString(v any) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case []byte:
+		return string(vv)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(vv)
+	}
+}
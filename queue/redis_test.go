@@ -0,0 +1,158 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	rd "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/redis"
+)
+
+func newTestRedisClient(t *testing.T) (*rd.Client, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := rd.NewClient(&rd.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client, mr
+}
+
+func TestRedis(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pub, sub, err := New(ctx, Config{Driver: DriverRedis, Group: "billing", RedisClient: client, RedisMaxLen: 100})
+	assert.Nil(t, err)
+	defer pub.Close()
+	defer sub.Close()
+
+	msg := NewMessage([]byte("hello"))
+	msg.ID = "order-1"
+	msg.Key = []byte("k1")
+	msg.Set("trace", "t1")
+	assert.Nil(t, pub.Publish(ctx, "orders", msg))
+
+	// XADD 写入 id、key、payload 和 metadata 字段
+	entries, err := mr.Stream("orders")
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Values, "order-1")
+
+	msgs, err := sub.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	got := <-msgs
+	assert.Equal(t, "order-1", got.ID)
+	assert.Equal(t, "orders", got.Topic)
+	assert.Equal(t, []byte("k1"), got.Key)
+	assert.Equal(t, []byte("hello"), got.Payload)
+	assert.Equal(t, map[string]string{"trace": "t1"}, got.Metadata)
+	assert.False(t, got.Timestamp.IsZero())
+
+	// XACK 后从 pending 列表移除
+	pending, err := client.XPending(ctx, "orders", "billing").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), pending.Count)
+	assert.Nil(t, got.Ack(ctx))
+	pending, err = client.XPending(ctx, "orders", "billing").Result()
+	assert.Nil(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestRedisNackRedelivery(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pub := NewRedisPublisher(client, 0)
+	first := NewRedisSubscriber(client, "billing", 50*time.Millisecond)
+	assert.Nil(t, pub.Publish(ctx, "orders", NewMessage([]byte("hello"))))
+
+	msgs, err := first.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	got := <-msgs
+	assert.Nil(t, got.Nack(ctx))
+	assert.Nil(t, first.Close())
+
+	// Nack 的消息空闲超过 claimIdle 后被同组的其他消费者通过 XAUTOCLAIM 认领
+	time.Sleep(100 * time.Millisecond)
+	second := NewRedisSubscriber(client, "billing", 50*time.Millisecond)
+	defer second.Close()
+	msgs, err = second.Subscribe(ctx, "orders")
+	assert.Nil(t, err)
+	select {
+	case redelivered := <-msgs:
+		assert.Equal(t, got.ID, redelivered.ID)
+		assert.Equal(t, []byte("hello"), redelivered.Payload)
+		assert.Nil(t, redelivered.Ack(ctx))
+	case <-ctx.Done():
+		t.Fatal("nacked message not redelivered")
+	}
+
+	_, err = first.Subscribe(ctx, "orders")
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRedisAutoClaimCursor(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	pub := NewRedisPublisher(client, 0)
+	sub := NewRedisSubscriber(client, "billing", time.Millisecond)
+	defer sub.Close()
+	assert.Nil(t, client.XGroupCreateMkStream(ctx, "orders", "billing", "0").Err())
+	for i := 0; i < claimCount+5; i++ {
+		assert.Nil(t, pub.Publish(ctx, "orders", NewMessage([]byte("hello"))))
+	}
+	// 其他消费者读取后未确认，全部留在 pending 列表中
+	assert.Nil(t, client.XReadGroup(ctx, &rd.XReadGroupArgs{
+		Group: "billing", Consumer: "other", Streams: []string{"orders", ">"}, Count: claimCount + 5,
+	}).Err())
+	time.Sleep(10 * time.Millisecond)
+
+	// 超过一页的 pending 消息通过游标继续认领，扫描完后游标回到 0
+	cursor := "0"
+	msgs, err := sub.read(ctx, "orders", &cursor)
+	assert.Nil(t, err)
+	assert.Len(t, msgs, claimCount)
+	assert.NotEqual(t, "0", cursor)
+
+	msgs, err = sub.read(ctx, "orders", &cursor)
+	assert.Nil(t, err)
+	assert.Len(t, msgs, 5)
+	assert.Equal(t, "0", cursor)
+}
+
+func TestRedisClientOwnership(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// New 创建的客户端在发布者和订阅者都关闭后关闭
+	pub, sub, err := New(ctx, Config{Driver: DriverRedis, Group: "billing", Redis: &redis.RedisOption{Addr: mr.Addr()}})
+	assert.Nil(t, err)
+	client := pub.(*RedisPublisher).client
+	assert.Nil(t, pub.Close())
+	assert.Nil(t, pub.Close())
+	assert.Nil(t, client.Ping(ctx).Err())
+	assert.Nil(t, sub.Close())
+	assert.ErrorIs(t, client.Ping(ctx).Err(), rd.ErrClosed)
+
+	// 外部传入的客户端不关闭
+	external, _ := newTestRedisClient(t)
+	pub, sub, err = New(ctx, Config{Driver: DriverRedis, Group: "billing", RedisClient: external})
+	assert.Nil(t, err)
+	assert.Nil(t, pub.Close())
+	assert.Nil(t, sub.Close())
+	assert.Nil(t, external.Ping(ctx).Err())
+
+	_, _, err = New(ctx, Config{Driver: DriverRedis})
+	assert.NotNil(t, err)
+
+	// 连接失败时返回错误而不是 panic
+	addr := mr.Addr()
+	mr.Close()
+	_, _, err = New(ctx, Config{Driver: DriverRedis, Redis: &redis.RedisOption{Addr: addr, MaxRetries: -1}})
+	assert.NotNil(t, err)
+}
@@ -8,8 +8,18 @@ import (
 	rd "github.com/redis/go-redis/v9"
 )
 
-// CreateClient create a client with option
+// CreateClient create a client with option, panics if the server is unreachable
 func CreateClient(ctx context.Context, opt *RedisOption) (*rd.Client, error) {
+	client, err := NewClient(ctx, opt)
+	if err != nil {
+		panic(fmt.Sprintf("failed to connect to goRds: %v", err))
+	}
+
+	return client, nil
+}
+
+// NewClient create a client with option, returns an error if the server is unreachable
+func NewClient(ctx context.Context, opt *RedisOption) (*rd.Client, error) {
 	opts := &rd.Options{
 		Addr:            opt.Addr,
 		Username:        opt.Username,
@@ -27,7 +37,8 @@ func CreateClient(ctx context.Context, opt *RedisOption) (*rd.Client, error) {
 	client := rd.NewClient(opts)
 
 	if err := client.Ping(ctx).Err(); err != nil {
-		panic(fmt.Sprintf("failed to connect to goRds: %v", err))
+		_ = client.Close()
+		return nil, err
 	}
 
 	return client, nil
@@ -1,58 +1,273 @@
 package closes
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
+
+	"github.com/betacats/go-core/utils/logx"
 )
 
 type (
 	ModuleClose struct {
 		Name     string
-		Priority int
-		Func     func()
+		Priority int                             // 越小越先关闭，相同优先级的 hook 并行执行
+		Timeout  time.Duration                   // 单个 hook 的超时时间，0 表示使用 SetHookTimeout 的默认值
+		Func     func(ctx context.Context) error // ctx 在超时或整体截止时间到达时被取消
 	}
 	closes []ModuleClose
 )
 
-var closeHandler closes
+var (
+	mu           sync.Mutex
+	closeHandler closes
+	shuttingDown atomic.Bool
+
+	// 整体关闭的截止时间（包含摘流等待）、单个 hook 的默认超时时间和执行 hook 前的摘流等待时间
+	shutdownTimeout = 30 * time.Second
+	hookTimeout     = 10 * time.Second
+	drainDelay      time.Duration
+
+	logger = logx.Module("closes")
+
+	// exit 退出进程，测试中替换以避免进程退出
+	exit = os.Exit
+)
+
+// exitGrace 整体截止时间到达后仍未退出时，再等待该时间后强制退出
+const exitGrace = time.Second
 
 const (
+	// AppPriority app 组件使用的优先级区间起点，[AppPriority, AppPriority+AppPriorityRange) 保留给 app 包，
+	// 区间内的 hook 先于其他所有 hook 关闭，其他 hook 不应使用该区间
+	AppPriority      = -10000
+	AppPriorityRange = 10000
+
 	MQPriority     = 100
 	GormPriority   = 500
 	MongoPriority  = 501
 	RedisPriority  = 510
+	OtelPriority   = 1900 // 在其他 hook 之后刷新，保证关闭过程中的 span 也能上报
 	AliLogPriority = 2000
 )
 
+const (
+	// ExitOK 所有 hook 正常关闭
+	ExitOK = 0
+	// ExitHookFailed 至少一个 hook 失败或超时
+	ExitHookFailed = 1
+	// ExitForced 关闭过程中再次收到信号，强制退出
+	ExitForced = 2
+	// ExitDeadline 超过整体截止时间仍未完成关闭
+	ExitDeadline = 3
+)
+
 func (c closes) Len() int           { return len(c) }
 func (c closes) Less(i, j int) bool { return c[i].Priority < c[j].Priority }
 func (c closes) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 
 // AddShutdown 增加程序结束时需要关闭的服务
 func AddShutdown(c ...ModuleClose) {
+	mu.Lock()
+	defer mu.Unlock()
 	closeHandler = append(closeHandler, c...)
 }
 
-// SignalClose 监听信号阻塞关闭
+// Hooks 返回已注册的 hook，按关闭顺序排列
+func Hooks() []ModuleClose {
+	mu.Lock()
+	hooks := make(closes, len(closeHandler))
+	copy(hooks, closeHandler)
+	mu.Unlock()
+
+	sort.Stable(hooks)
+	return hooks
+}
+
+// SetTimeout 设置 Close 整体的截止时间，包含摘流等待，默认 30s
+func SetTimeout(d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	shutdownTimeout = d
+}
+
+// SetHookTimeout 设置单个 hook 默认的超时时间，默认 10s
+func SetHookTimeout(d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	hookTimeout = d
+}
+
+// SetDrainDelay 设置执行 hook 前的摘流等待时间，默认 0。
+// 等待期间 IsShuttingDown 返回 true，readiness 失败，负载均衡有时间摘除实例
+func SetDrainDelay(d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	drainDelay = d
+}
+
+// IsShuttingDown 返回进程是否已开始关闭，readiness 检查据此尽早摘除流量
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// SignalClose 监听信号阻塞关闭，关闭过程中再次收到信号时以 ExitForced 立即退出
 func SignalClose() {
-	c := make(chan os.Signal, 1)
+	c := make(chan os.Signal, 2)
 	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGTSTP)
+	signalClose(c)
+}
+
+func signalClose(c <-chan os.Signal) {
 	sig := <-c
-	fmt.Printf("Got %s signal. Aborting...\n", sig)
+	logger.Info("got signal, shutting down", "signal", sig.String())
+	go func() {
+		sig := <-c
+		logger.Warn("got signal again, force exit", "signal", sig.String())
+		exit(ExitForced)
+	}()
 	Close()
 }
 
-// Close 按照优先级调用关闭方法
+// Close 摘流等待后按照优先级调用关闭方法并退出进程。
+// 全部成功时以 ExitOK 退出，有 hook 失败或超时时以 ExitHookFailed 退出，
+// 超过整体截止时间时以 ExitDeadline 退出
 func Close() {
-	sort.Sort(closeHandler)
-	if len(closeHandler) > 0 {
-		for _, f := range closeHandler {
-			fmt.Printf("Close %s ...\n", f.Name)
-			f.Func()
+	exit(closeAll())
+}
+
+func closeAll() int {
+	shuttingDown.Store(true)
+
+	mu.Lock()
+	d, drain := shutdownTimeout, drainDelay
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	// hook 不响应 ctx 时 Shutdown 也会在截止时间返回，这里兜底避免进程卡住
+	watchdog := time.AfterFunc(d+exitGrace, func() {
+		logger.Error("shutdown exceeded deadline, force exit", "timeout", d.String())
+		exit(ExitDeadline)
+	})
+	defer watchdog.Stop()
+
+	if drain > 0 {
+		logger.Info("draining", "delay", drain.String())
+		timer := time.NewTimer(drain)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	err := Shutdown(ctx)
+	switch {
+	case ctx.Err() != nil:
+		logger.Error("shutdown exceeded deadline", "timeout", d.String(), "error", err)
+		return ExitDeadline
+	case err != nil:
+		logger.Error("shutdown failed", "error", err)
+		return ExitHookFailed
+	}
+	return ExitOK
+}
+
+// Shutdown 按照优先级调用关闭方法但不退出进程，相同优先级的 hook 并行执行。
+// 某个 hook 失败不会影响后续 hook 的执行，ctx 到期后剩余的 hook 被跳过，
+// 返回值汇总了所有失败、超时和被跳过的 hook
+func Shutdown(ctx context.Context) error {
+	shuttingDown.Store(true)
+
+	mu.Lock()
+	hooks := make(closes, len(closeHandler))
+	copy(hooks, closeHandler)
+	defaultTimeout := hookTimeout
+	mu.Unlock()
+
+	sort.Stable(hooks)
+
+	var errs []error
+	for start := 0; start < len(hooks); {
+		end := start + 1
+		for end < len(hooks) && hooks[end].Priority == hooks[start].Priority {
+			end++
+		}
+		errs = append(errs, runGroup(ctx, hooks[start:end], defaultTimeout)...)
+		start = end
+	}
+	return errors.Join(errs...)
+}
+
+// runGroup 并行执行同一优先级的 hook
+func runGroup(ctx context.Context, group closes, defaultTimeout time.Duration) []error {
+	errs := make([]error, len(group))
+	var wg sync.WaitGroup
+	for i, f := range group {
+		if err := ctx.Err(); err != nil {
+			errs[i] = fmt.Errorf("close %s: skipped: %w", f.Name, err)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, f ModuleClose) {
+			defer wg.Done()
+			errs[i] = runHook(ctx, f, defaultTimeout)
+		}(i, f)
+	}
+	wg.Wait()
+
+	res := errs[:0]
+	for _, err := range errs {
+		if err != nil {
+			res = append(res, err)
+		}
+	}
+	return res
+}
+
+// runHook 在超时时间内执行单个 hook，hook 不响应 ctx 取消时不再等待其返回
+func runHook(ctx context.Context, f ModuleClose, defaultTimeout time.Duration) error {
+	logger.InfoContext(ctx, "close hook", "hook", f.Name)
+	if f.Func == nil {
+		return nil
+	}
+
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- f.Func(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("close %s: %w", f.Name, err)
 		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("close %s: %w", f.Name, ctx.Err())
 	}
-	os.Exit(0)
 }
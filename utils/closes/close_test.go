@@ -1,8 +1,14 @@
 package closes
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -14,8 +20,9 @@ func TestName(t *testing.T) {
 	AddShutdown(ModuleClose{
 		Name:     "http",
 		Priority: 0,
-		Func: func() {
+		Func: func(ctx context.Context) error {
 			fmt.Println("close http")
+			return nil
 		},
 	})
 	go SignalClose()
@@ -28,14 +35,16 @@ func TestSort(t *testing.T) {
 	closeHandler = append(closeHandler, ModuleClose{
 		Name:     "grom",
 		Priority: 100,
-		Func: func() {
+		Func: func(ctx context.Context) error {
 			fmt.Println("close http")
+			return nil
 		},
 	}, ModuleClose{
 		Name:     "http",
 		Priority: 0,
-		Func: func() {
+		Func: func(ctx context.Context) error {
 			fmt.Println("close http")
+			return nil
 		},
 	})
 
@@ -44,3 +53,233 @@ func TestSort(t *testing.T) {
 	assert.Equal(t, closeHandler[0].Priority, 0)
 
 }
+
+// resetHandlers 清空已注册的 hook，并在测试结束后恢复
+func resetHandlers(t *testing.T) {
+	saved := closeHandler
+	closeHandler = nil
+	t.Cleanup(func() { closeHandler = saved })
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("order and parallel", func(t *testing.T) {
+		resetHandlers(t)
+
+		var (
+			mu    sync.Mutex
+			order []string
+		)
+		record := func(name string) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, name)
+				return nil
+			}
+		}
+
+		// 相同优先级的 hook 并行执行，互相等待也不会死锁
+		barrier := make(chan struct{}, 2)
+		wait := func(name string) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				barrier <- struct{}{}
+				for len(barrier) < 2 {
+					time.Sleep(time.Millisecond)
+				}
+				return record(name)(ctx)
+			}
+		}
+
+		AddShutdown(
+			ModuleClose{Name: "redis", Priority: RedisPriority, Func: record("redis")},
+			ModuleClose{Name: "kafka", Priority: MQPriority, Func: wait("kafka")},
+			ModuleClose{Name: "http", Priority: MQPriority, Func: wait("http")},
+			ModuleClose{Name: "gorm", Priority: GormPriority, Func: record("gorm")},
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.Nil(t, Shutdown(ctx))
+		assert.ElementsMatch(t, []string{"kafka", "http"}, order[:2])
+		assert.Equal(t, []string{"gorm", "redis"}, order[2:])
+	})
+
+	t.Run("aggregate errors", func(t *testing.T) {
+		resetHandlers(t)
+
+		errGorm := errors.New("gorm close failed")
+		var redisClosed bool
+		AddShutdown(
+			ModuleClose{Name: "gorm", Priority: GormPriority, Func: func(ctx context.Context) error {
+				return errGorm
+			}},
+			ModuleClose{Name: "slow", Priority: GormPriority, Timeout: 20 * time.Millisecond, Func: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+			ModuleClose{Name: "stuck", Priority: GormPriority, Timeout: 20 * time.Millisecond, Func: func(ctx context.Context) error {
+				select {}
+			}},
+			ModuleClose{Name: "panic", Priority: GormPriority, Func: func(ctx context.Context) error {
+				panic("boom")
+			}},
+			ModuleClose{Name: "redis", Priority: RedisPriority, Func: func(ctx context.Context) error {
+				redisClosed = true
+				return nil
+			}},
+		)
+
+		err := Shutdown(context.Background())
+		assert.ErrorIs(t, err, errGorm)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "close slow")
+		assert.Contains(t, err.Error(), "close stuck")
+		assert.Contains(t, err.Error(), "close panic: panic: boom")
+		// 前面的 hook 失败不影响后续 hook
+		assert.True(t, redisClosed)
+	})
+
+	t.Run("deadline skips remaining", func(t *testing.T) {
+		resetHandlers(t)
+
+		var redisClosed bool
+		AddShutdown(
+			ModuleClose{Name: "gorm", Priority: GormPriority, Func: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+			ModuleClose{Name: "redis", Priority: RedisPriority, Func: func(ctx context.Context) error {
+				redisClosed = true
+				return nil
+			}},
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := Shutdown(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "close redis: skipped")
+		assert.False(t, redisClosed)
+	})
+}
+
+// stubExit 替换进程退出函数，返回记录退出码的 channel
+func stubExit(t *testing.T) <-chan int {
+	codes := make(chan int, 4)
+	saved := exit
+	exit = func(code int) { codes <- code }
+	t.Cleanup(func() { exit = saved })
+	return codes
+}
+
+// setTimeouts 修改超时配置，并在测试结束后恢复
+func setTimeouts(t *testing.T, timeout, drain time.Duration) {
+	savedTimeout, savedDrain := shutdownTimeout, drainDelay
+	SetTimeout(timeout)
+	SetDrainDelay(drain)
+	t.Cleanup(func() {
+		SetTimeout(savedTimeout)
+		SetDrainDelay(savedDrain)
+		shuttingDown.Store(false)
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("drain before hooks", func(t *testing.T) {
+		resetHandlers(t)
+		setTimeouts(t, time.Second, 50*time.Millisecond)
+		codes := stubExit(t)
+
+		var readyDuringDrain, closed atomic.Bool
+		AddShutdown(ModuleClose{Name: "http", Func: func(ctx context.Context) error {
+			closed.Store(true)
+			return nil
+		}})
+
+		go Close()
+		time.Sleep(20 * time.Millisecond)
+		readyDuringDrain.Store(!IsShuttingDown())
+		assert.False(t, closed.Load())
+
+		assert.Equal(t, ExitOK, <-codes)
+		assert.False(t, readyDuringDrain.Load())
+		assert.True(t, closed.Load())
+	})
+
+	t.Run("hook failed", func(t *testing.T) {
+		resetHandlers(t)
+		setTimeouts(t, time.Second, 0)
+		codes := stubExit(t)
+
+		AddShutdown(ModuleClose{Name: "gorm", Func: func(ctx context.Context) error {
+			return errors.New("gorm close failed")
+		}})
+
+		Close()
+		assert.Equal(t, ExitHookFailed, <-codes)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		resetHandlers(t)
+		setTimeouts(t, 30*time.Millisecond, 10*time.Millisecond)
+		codes := stubExit(t)
+
+		AddShutdown(ModuleClose{Name: "stuck", Timeout: time.Minute, Func: func(ctx context.Context) error {
+			select {}
+		}})
+
+		Close()
+		assert.Equal(t, ExitDeadline, <-codes)
+	})
+
+	t.Run("second signal", func(t *testing.T) {
+		resetHandlers(t)
+		setTimeouts(t, time.Second, 0)
+		codes := stubExit(t)
+
+		release := make(chan struct{})
+		AddShutdown(ModuleClose{Name: "slow", Func: func(ctx context.Context) error {
+			<-release
+			return nil
+		}})
+
+		sigs := make(chan os.Signal, 2)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			signalClose(sigs)
+		}()
+		sigs <- syscall.SIGTERM
+		time.Sleep(10 * time.Millisecond)
+		sigs <- syscall.SIGINT
+
+		select {
+		case code := <-codes:
+			assert.Equal(t, ExitForced, code)
+		case <-time.After(time.Second):
+			t.Fatal("second signal did not force exit")
+		}
+
+		// 等待关闭流程结束后再恢复 exit
+		close(release)
+		<-done
+		assert.Equal(t, ExitOK, <-codes)
+	})
+}
+
+func TestHooks(t *testing.T) {
+	resetHandlers(t)
+	AddShutdown(
+		ModuleClose{Name: "redis", Priority: RedisPriority},
+		ModuleClose{Name: "kafka", Priority: MQPriority},
+		ModuleClose{Name: "gorm", Priority: GormPriority},
+	)
+
+	hooks := Hooks()
+	assert.Len(t, hooks, 3)
+	assert.Equal(t, "kafka", hooks[0].Name)
+	assert.Equal(t, "gorm", hooks[1].Name)
+	assert.Equal(t, "redis", hooks[2].Name)
+	// 返回的是副本，不影响注册顺序
+	assert.Equal(t, "redis", closeHandler[0].Name)
+}
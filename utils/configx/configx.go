@@ -0,0 +1,110 @@
+package configx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/betacats/go-core/utils/mapping"
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+// defaultSource 未被任何配置源提供、由 default 标签填充的字段在报告中的来源
+const defaultSource = "default"
+
+type (
+	// Source 配置源，返回的 map 与配置文件的结构一致。
+	// keys 为目标结构体所有叶子字段的路径（小写，以 . 分隔），环境变量和命令行参数等扁平的配置源据此查找
+	Source interface {
+		Name() string
+		Load(ctx context.Context, keys []string) (map[string]any, error)
+	}
+
+	// Loader 按优先级合并多个配置源，后面的配置源覆盖前面的，
+	// 推荐顺序为 Defaults < File < Nacos < Env < Flags
+	Loader struct {
+		sources []Source
+	}
+
+	// Report 记录每个字段最终取值的来源，key 为字段路径，如 Mysql.Host
+	Report struct {
+		Fields map[string]string
+	}
+)
+
+// NewLoader 创建配置加载器，sources 按优先级从低到高排列
+func NewLoader(sources ...Source) *Loader {
+	return &Loader{sources: sources}
+}
+
+// Load 依次加载所有配置源并深度合并，通过 mapping 解析到 v，返回各字段的来源
+func (l *Loader) Load(ctx context.Context, v any) (*Report, error) {
+	rt := reflect.TypeOf(v)
+	if rt == nil || rt.Kind() != reflect.Pointer || rt.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("configx: v must be a pointer to struct")
+	}
+	root := buildFields(rt.Elem(), "")
+	keys := root.leafKeys("")
+
+	report := &Report{Fields: make(map[string]string)}
+	merged := make(map[string]any)
+	for _, src := range l.sources {
+		m, err := src.Load(ctx, keys)
+		if err != nil {
+			return nil, fmt.Errorf("configx: source %s: %w", src.Name(), err)
+		}
+		m, err = root.normalize(m, func(path string) {
+			report.Fields[path] = src.Name()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("configx: source %s: %w", src.Name(), err)
+		}
+		merged = nacosx.MergeMap(merged, m)
+	}
+
+	if err := mapping.UnmarshalJsonMap(merged, v, mapping.WithCanonicalKeyFunc(strings.ToLower)); err != nil {
+		return nil, err
+	}
+	root.defaults(func(path string) {
+		if _, ok := report.Fields[path]; !ok {
+			report.Fields[path] = defaultSource
+		}
+	})
+	return report, nil
+}
+
+// MustLoad 加载配置到 v，失败时退出进程
+func (l *Loader) MustLoad(ctx context.Context, v any) *Report {
+	report, err := l.Load(ctx, v)
+	if err != nil {
+		log.Fatalf("error: config, %s", err.Error())
+	}
+	return report
+}
+
+// Source 返回字段最终取值的来源，没有任何来源时返回空字符串
+func (r *Report) Source(path string) string {
+	return r.Fields[path]
+}
+
+// String 按字段路径排序输出每个字段的来源
+func (r *Report) String() string {
+	paths := make([]string, 0, len(r.Fields))
+	for path := range r.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		sb.WriteString(path)
+		sb.WriteString(" = ")
+		sb.WriteString(r.Fields[path])
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
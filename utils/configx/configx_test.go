@@ -0,0 +1,138 @@
+package configx
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+type MysqlConf struct {
+	Host     string
+	Port     int `json:",default=3306"`
+	Password string
+}
+
+type testConfig struct {
+	Name    string
+	Debug   bool          `json:",optional"`
+	Timeout time.Duration `json:",default=1s"`
+	Hosts   []string      `json:",optional"`
+	Mysql   MysqlConf
+	Labels  map[string]string `json:"tags,optional"`
+}
+
+func TestLoader(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(`
+Name: demo
+mysql:
+  host: file-db
+  password: ${DB_PASSWORD}
+tags:
+  Team: core
+`), 0o644))
+	t.Setenv("DB_PASSWORD", "s3cret")
+	t.Setenv("APP_MYSQL_HOST", "env-db")
+	t.Setenv("APP_HOSTS", "a, b")
+	t.Setenv("APP_TIMEOUT", "5s")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("mysql.port", 0, "")
+	fs.Bool("debug", false, "")
+	fs.String("unused", "", "")
+	assert.Nil(t, fs.Parse([]string{"-mysql.port=3307", "-debug"}))
+
+	var c testConfig
+	report, err := NewLoader(
+		Defaults(map[string]any{"name": "default-name", "mysql": map[string]any{"host": "localhost"}}),
+		File(file, nacosx.UseEnv()),
+		Env("APP"),
+		Flags(fs),
+	).Load(context.Background(), &c)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "demo", c.Name)
+	assert.True(t, c.Debug)
+	assert.Equal(t, 5*time.Second, c.Timeout)
+	assert.Equal(t, []string{"a", "b"}, c.Hosts)
+	assert.Equal(t, MysqlConf{Host: "env-db", Port: 3307, Password: "s3cret"}, c.Mysql)
+	assert.Equal(t, map[string]string{"Team": "core"}, c.Labels)
+
+	assert.Equal(t, "file:"+file, report.Source("Name"))
+	assert.Equal(t, "env", report.Source("Mysql.Host"))
+	assert.Equal(t, "flags", report.Source("Mysql.Port"))
+	assert.Equal(t, "flags", report.Source("Debug"))
+	assert.Equal(t, "env", report.Source("Timeout"))
+	assert.Equal(t, "file:"+file, report.Source("Labels"))
+	assert.Contains(t, report.String(), "Mysql.Host = env\n")
+}
+
+func TestLoaderDefaultsAndErrors(t *testing.T) {
+	var c testConfig
+	report, err := NewLoader(
+		Defaults(map[string]any{"name": "demo", "mysql": map[string]any{"host": "localhost", "password": ""}}),
+	).Load(context.Background(), &c)
+	assert.Nil(t, err)
+	assert.Equal(t, 3306, c.Mysql.Port)
+	assert.Equal(t, time.Second, c.Timeout)
+	assert.Equal(t, "default", report.Source("Mysql.Port"))
+	assert.Equal(t, "defaults", report.Source("Mysql.Host"))
+
+	t.Setenv("BAD_MYSQL_PORT", "abc")
+	_, err = NewLoader(Env("BAD")).Load(context.Background(), &c)
+	assert.ErrorContains(t, err, "source env: field Mysql.Port")
+
+	_, err = NewLoader(File("missing.yaml")).Load(context.Background(), &c)
+	assert.ErrorContains(t, err, "source file:missing.yaml")
+
+	_, err = NewLoader().Load(context.Background(), c)
+	assert.NotNil(t, err)
+
+	// 必填字段缺失
+	_, err = NewLoader(Defaults(map[string]any{"name": "demo"})).Load(context.Background(), &testConfig{})
+	assert.NotNil(t, err)
+}
+
+func TestLoaderRecursiveType(t *testing.T) {
+	type node struct {
+		Name string `json:",optional"`
+		Next *node  `json:",optional"`
+	}
+	var c struct {
+		Head node
+		Tail node
+	}
+	report, err := NewLoader(Defaults(map[string]any{
+		"head": map[string]any{"name": "a"},
+		"tail": map[string]any{"name": "z"},
+	})).Load(context.Background(), &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", c.Head.Name)
+	assert.Equal(t, "z", c.Tail.Name)
+	assert.Equal(t, "defaults", report.Source("Tail.Name"))
+}
+
+func TestEnvAmbiguousKeys(t *testing.T) {
+	var c struct {
+		A struct {
+			BC string `json:"b_c,optional"`
+		}
+		AB struct {
+			C string `json:",optional"`
+		} `json:"a_b"`
+	}
+	_, err := NewLoader(Env("AMB")).Load(context.Background(), &c)
+	assert.Nil(t, err)
+
+	t.Setenv("AMB_A_B_C", "x")
+	_, err = NewLoader(Env("AMB")).Load(context.Background(), &c)
+	assert.ErrorContains(t, err, "env AMB_A_B_C is ambiguous for fields a.b_c and a_b.c")
+}
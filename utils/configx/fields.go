@@ -0,0 +1,173 @@
+package configx
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// field 目标结构体的字段树，children 的 key 为小写的字段名
+type field struct {
+	path       string // 以 Go 字段名表示的路径，用于报告
+	typ        reflect.Type
+	hasDefault bool
+	children   map[string]*field
+}
+
+func buildFields(t reflect.Type, path string) *field {
+	return build(t, path, make(map[reflect.Type]bool))
+}
+
+// build 构建字段树，ancestors 为当前路径上的结构体类型，再次出现的类型作为叶子，避免自引用类型无限递归
+func build(t reflect.Type, path string, ancestors map[reflect.Type]bool) *field {
+	f := &field{path: path, typ: t}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(textUnmarshalerType) || ancestors[t] {
+		return f
+	}
+	ancestors[t] = true
+	defer delete(ancestors, t)
+
+	f.children = make(map[string]*field)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// 没有指定名称的嵌入结构体字段展开到当前层级
+		if sf.Anonymous && name == "" {
+			embedded := build(sf.Type, path, ancestors)
+			for k, child := range embedded.children {
+				f.children[k] = child
+			}
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		child := build(sf.Type, join(path, sf.Name), ancestors)
+		child.hasDefault = strings.Contains(opts, "default=")
+		f.children[strings.ToLower(name)] = child
+	}
+	return f
+}
+
+// leafKeys 返回所有叶子字段的小写路径
+func (f *field) leafKeys(prefix string) []string {
+	if f.children == nil {
+		return []string{prefix}
+	}
+	var keys []string
+	for name, child := range f.children {
+		keys = append(keys, child.leafKeys(join(prefix, name))...)
+	}
+	return keys
+}
+
+// normalize 将结构体字段对应的 key 转为小写，并将字符串按字段类型转换，对每个提供的叶子字段回调 visit
+func (f *field) normalize(m map[string]any, visit func(path string)) (map[string]any, error) {
+	res := make(map[string]any, len(m))
+	for k, v := range m {
+		child, ok := f.children[strings.ToLower(k)]
+		if !ok {
+			res[k] = v
+			continue
+		}
+		key := strings.ToLower(k)
+		if sub, ok := v.(map[string]any); ok && child.children != nil {
+			nv, err := child.normalize(sub, visit)
+			if err != nil {
+				return nil, err
+			}
+			res[key] = nv
+			continue
+		}
+
+		nv, err := coerce(child.typ, v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", child.path, err)
+		}
+		res[key] = nv
+		visit(child.path)
+	}
+	return res, nil
+}
+
+// defaults 对带有 default 标签的叶子字段回调 visit
+func (f *field) defaults(visit func(path string)) {
+	for _, child := range f.children {
+		if child.children == nil {
+			if child.hasDefault {
+				visit(child.path)
+			}
+			continue
+		}
+		child.defaults(visit)
+	}
+}
+
+// coerce 将环境变量、命令行参数等字符串值转换为字段类型对应的值
+func coerce(t reflect.Type, v any) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == durationType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return s, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q", s)
+		}
+		return json.Number(s), nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %q", s)
+		}
+		return b, nil
+	case reflect.Slice:
+		var items []any
+		for _, item := range strings.Split(s, ",") {
+			nv, err := coerce(t.Elem(), strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, nv)
+		}
+		return items, nil
+	default:
+		return s, nil
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,144 @@
+package configx
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+type (
+	defaultsSource struct {
+		values map[string]any
+	}
+
+	fileSource struct {
+		path string
+		opts []nacosx.Option
+	}
+
+	nacosSource struct {
+		n    *nacosx.Nacosx
+		opts []nacosx.Option
+	}
+
+	envSource struct {
+		prefix string
+	}
+
+	flagSource struct {
+		fs *flag.FlagSet
+	}
+)
+
+// Defaults 代码中指定的默认值，优先级最低。结构体的 default 标签同样生效
+func Defaults(values map[string]any) Source {
+	return &defaultsSource{values: values}
+}
+
+func (s *defaultsSource) Name() string {
+	return "defaults"
+}
+
+func (s *defaultsSource) Load(context.Context, []string) (map[string]any, error) {
+	return s.values, nil
+}
+
+// File 本地配置文件，支持 .json、.yaml、.yml 和 .toml，可使用 nacosx.UseEnv 展开环境变量
+func File(path string, opts ...nacosx.Option) Source {
+	return &fileSource{path: path, opts: opts}
+}
+
+func (s *fileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *fileSource) Load(context.Context, []string) (map[string]any, error) {
+	return nacosx.LoadMap(s.path, s.opts...)
+}
+
+// Nacos nacos 配置中心，合并了 Nacosx 的所有配置源
+func Nacos(n *nacosx.Nacosx, opts ...nacosx.Option) Source {
+	return &nacosSource{n: n, opts: opts}
+}
+
+func (s *nacosSource) Name() string {
+	return "nacos"
+}
+
+func (s *nacosSource) Load(context.Context, []string) (map[string]any, error) {
+	return s.n.LoadMap(s.opts...)
+}
+
+// Env 环境变量，字段路径 mysql.maxConns 对应 <PREFIX>_MYSQL_MAXCONNS，数组使用逗号分隔。
+// 多个字段对应同一个环境变量（如 a.b_c 和 a_b.c）且该变量已设置时返回错误
+func Env(prefix string) Source {
+	return &envSource{prefix: prefix}
+}
+
+func (s *envSource) Name() string {
+	return "env"
+}
+
+func (s *envSource) Load(_ context.Context, keys []string) (map[string]any, error) {
+	m := make(map[string]any)
+	seen := make(map[string]string, len(keys))
+	for _, key := range keys {
+		name := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if s.prefix != "" {
+			name = strings.ToUpper(s.prefix) + "_" + name
+		}
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if prev, dup := seen[name]; dup {
+			return nil, fmt.Errorf("env %s is ambiguous for fields %s and %s", name, min(prev, key), max(prev, key))
+		}
+		seen[name] = key
+		setPath(m, key, v)
+	}
+	return m, nil
+}
+
+// Flags 命令行参数，只使用显式设置过的参数，参数名为字段路径，如 -mysql.host，忽略大小写
+func Flags(fs *flag.FlagSet) Source {
+	return &flagSource{fs: fs}
+}
+
+func (s *flagSource) Name() string {
+	return "flags"
+}
+
+func (s *flagSource) Load(_ context.Context, keys []string) (map[string]any, error) {
+	known := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		known[key] = true
+	}
+
+	m := make(map[string]any)
+	s.fs.Visit(func(f *flag.Flag) {
+		key := strings.ToLower(f.Name)
+		if known[key] {
+			setPath(m, key, f.Value.String())
+		}
+	})
+	return m, nil
+}
+
+// setPath 按 . 分隔的路径设置嵌套 map 中的值
+func setPath(m map[string]any, path string, v any) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := m[part].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			m[part] = sub
+		}
+		m = sub
+	}
+	m[parts[len(parts)-1]] = v
+}
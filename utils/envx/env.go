@@ -1,6 +1,13 @@
 package envx
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Env 运行环境
+type Env string
 
 const (
 	// EnvDev 开发环境
@@ -14,6 +21,8 @@ const (
 )
 
 var (
+	mu sync.RWMutex
+
 	envMap = map[string]string{
 		"dev":  EnvDev,
 		"test": EnvTest,
@@ -23,35 +32,152 @@ var (
 
 	// 存储自定义的环境值，优先于系统环境变量
 	customOSEnv string
+
+	// 严格模式下环境变量为空或不在映射表中时报错，而不是回退为开发环境
+	strict bool
+
+	// 首次解析后缓存结果，修改配置或调用 Reload 时失效
+	cached *resolved
+
+	// 测试中覆盖的环境，优先于环境变量
+	override Env
 )
 
+type resolved struct {
+	env Env
+	err error
+}
+
 // SetOSEnv 设置自定义环境值，会覆盖系统环境变量中的ENV值
 func SetOSEnv(env string) {
+	mu.Lock()
+	defer mu.Unlock()
 	customOSEnv = env
+	cached = nil
 }
 
 // SetEnvMap 自定义环境变量映射表，用于扩展或修改环境变量映射关系
 func SetEnvMap(m map[string]string) {
-	if m != nil {
-		envMap = m
+	if m == nil {
+		return
+	}
+	cp := make(map[string]string, len(m))
+	for k, v := range m {
+		cp[k] = v
 	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	envMap = cp
+	cached = nil
 }
 
-// ENV
-func ENV() string {
-	env := os.Getenv("ENV")
+// SetStrict 设置严格模式，开启后 Resolve 对未设置或未知的环境值返回错误。
+// 生产服务应在启动时开启并调用 MustResolve，避免拼写错误导致以开发环境配置运行
+func SetStrict(s bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	strict = s
+	cached = nil
+}
+
+// Resolve 解析当前环境，非严格模式下未知的环境值回退为开发环境。
+// 结果在首次解析后缓存，环境变量变化后需要调用 Reload
+func Resolve() (Env, error) {
+	mu.RLock()
+	c := cached
+	mu.RUnlock()
+	if c != nil {
+		return c.env, c.err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if cached == nil {
+		cached = resolveLocked()
+	}
+	return cached.env, cached.err
+}
+
+// Reload 丢弃缓存并重新解析当前环境
+func Reload() (Env, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	cached = resolveLocked()
+	return cached.env, cached.err
+}
+
+func resolveLocked() *resolved {
+	if override != "" {
+		return &resolved{env: override}
+	}
+
+	key := "ENV"
 	// 优先使用自定义设置的环境值
 	if customOSEnv != "" {
-		env = os.Getenv(customOSEnv)
+		key = customOSEnv
 	}
+	env := os.Getenv(key)
 
 	if result, ok := envMap[env]; ok {
-		return result
+		return &resolved{env: Env(result)}
+	}
+	if strict {
+		return &resolved{err: fmt.Errorf("envx: unknown environment %s=%q", key, env)}
+	}
+	return &resolved{env: EnvDev}
+}
+
+// MustResolve 解析当前环境，严格模式下环境值未知时 panic，应在启动时调用以拒绝启动
+func MustResolve() Env {
+	env, err := Resolve()
+	if err != nil {
+		panic(err)
 	}
-	return EnvDev
+	return env
+}
+
+// Current 返回当前环境，不会 panic，严格模式下环境值未知时返回开发环境，
+// 启动时应通过 Resolve 或 MustResolve 检查
+func Current() Env {
+	env, err := Resolve()
+	if err != nil {
+		return EnvDev
+	}
+	return env
+}
+
+// ENV 返回当前环境的字符串值
+func ENV() string {
+	return string(Current())
 }
 
 // Get 获取某个环境变量的值
 func Get(str string) string {
 	return os.Getenv(str)
 }
+
+// String 实现 fmt.Stringer
+func (e Env) String() string {
+	return string(e)
+}
+
+// IsDev 是否为开发环境
+func (e Env) IsDev() bool {
+	return e == EnvDev
+}
+
+// IsTest 是否为测试环境
+func (e Env) IsTest() bool {
+	return e == EnvTest
+}
+
+// IsUat 是否为灰度环境
+func (e Env) IsUat() bool {
+	return e == EnvUat
+}
+
+// IsProd 是否为生产环境
+func (e Env) IsProd() bool {
+	return e == EnvProd
+}
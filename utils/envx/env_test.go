@@ -2,7 +2,10 @@ package envx
 
 import (
 	"os"
+	"sync"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGet(t *testing.T) {
@@ -13,3 +16,80 @@ func TestGet(t *testing.T) {
 	e := ENV()
 	t.Log(e)
 }
+
+func TestResolve(t *testing.T) {
+	t.Cleanup(func() {
+		SetOSEnv("")
+		SetEnvMap(map[string]string{"dev": EnvDev, "test": EnvTest, "uat": EnvUat, "prod": EnvProd})
+		SetStrict(false)
+	})
+	SetOSEnv("")
+	SetEnvMap(map[string]string{"dev": EnvDev, "test": EnvTest, "uat": EnvUat, "prod": EnvProd})
+
+	t.Setenv("ENV", "prod")
+	_, _ = Reload()
+	assert.True(t, Current().IsProd())
+	assert.False(t, Current().IsDev())
+	assert.Equal(t, EnvProd, ENV())
+
+	// 非严格模式下拼写错误回退为开发环境
+	t.Setenv("ENV", "prdo")
+	// 缓存生效，环境变量变化后需要 Reload
+	assert.True(t, Current().IsProd())
+	_, _ = Reload()
+	assert.True(t, Current().IsDev())
+
+	SetStrict(true)
+	_, err := Resolve()
+	assert.ErrorContains(t, err, `ENV="prdo"`)
+	assert.Panics(t, func() { MustResolve() })
+	// 请求路径上的 Current 和 ENV 不会 panic
+	assert.True(t, Current().IsDev())
+	assert.Equal(t, EnvDev, ENV())
+
+	t.Setenv("ENV", "")
+	_, err = Reload()
+	assert.NotNil(t, err)
+
+	t.Setenv("ENV", "uat")
+	env, err := Reload()
+	assert.Nil(t, err)
+	assert.True(t, env.IsUat())
+}
+
+func TestSetForTest(t *testing.T) {
+	t.Setenv("ENV", "dev")
+	_, _ = Reload()
+
+	t.Run("override", func(t *testing.T) {
+		SetForTest(t, EnvProd)
+		assert.True(t, Current().IsProd())
+		// Reload 不会丢弃覆盖
+		env, _ := Reload()
+		assert.True(t, env.IsProd())
+	})
+	assert.True(t, Current().IsDev())
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	t.Cleanup(func() { SetOSEnv("") })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = ENV()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				SetOSEnv("ENV")
+				_, _ = Reload()
+			}
+		}()
+	}
+	wg.Wait()
+}
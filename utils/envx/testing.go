@@ -0,0 +1,22 @@
+package envx
+
+import "testing"
+
+// SetForTest 在当前测试中将环境覆盖为 env，测试结束后恢复。
+// 覆盖对整个进程生效，使用它的测试不能并行执行
+func SetForTest(t testing.TB, env Env) {
+	t.Helper()
+
+	mu.Lock()
+	prev := override
+	override = env
+	cached = nil
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		override = prev
+		cached = nil
+	})
+}
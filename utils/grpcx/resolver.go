@@ -0,0 +1,175 @@
+package grpcx
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/betacats/go-core/utils/logx"
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+// Scheme nacos 服务发现的 target scheme，如 nacos:///order-service 或 nacos://SHARED/order-service?clusters=a,b&metadata.zone=a
+const Scheme = "nacos"
+
+const metadataPrefix = "metadata."
+
+var logger = logx.Module("grpcx")
+
+type (
+	// Subscriber 订阅服务实例变化，*nacosx.Resolver 实现了该接口
+	Subscriber interface {
+		Subscribe(t nacosx.Target, fn func(instances []model.Instance)) (func(), error)
+	}
+
+	attrKey string
+
+	// metadata 实现 Equal，attributes 比较时 map 不能直接使用 ==
+	metadata map[string]string
+
+	builder struct {
+		sub Subscriber
+	}
+
+	nacosResolver struct {
+		sub    Subscriber
+		target nacosx.Target
+		cc     resolver.ClientConn
+
+		mu     sync.Mutex
+		cancel func()
+		closed bool
+	}
+)
+
+var _ Subscriber = (*nacosx.Resolver)(nil)
+
+const (
+	weightKey   attrKey = "nacos.weight"
+	metadataKey attrKey = "nacos.metadata"
+)
+
+// Register 以 nacos scheme 全局注册 resolver，应在 init 或创建 grpc 客户端之前调用
+func Register(sub Subscriber) {
+	resolver.Register(NewBuilder(sub))
+}
+
+// NewBuilder 创建 nacos resolver.Builder，可以通过 grpc.WithResolvers 只用于单个客户端
+func NewBuilder(sub Subscriber) resolver.Builder {
+	return &builder{sub: sub}
+}
+
+// Weight 返回地址对应实例的权重
+func Weight(addr resolver.Address) float64 {
+	w, _ := addr.Attributes.Value(weightKey).(float64)
+	return w
+}
+
+// Metadata 返回地址对应实例的元数据
+func Metadata(addr resolver.Address) map[string]string {
+	md, _ := addr.Attributes.Value(metadataKey).(metadata)
+	return md
+}
+
+func (m metadata) Equal(o any) bool {
+	om, ok := o.(metadata)
+	return ok && maps.Equal(m, om)
+}
+
+// Scheme 实现 resolver.Builder
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+// Build 实现 resolver.Builder，订阅失败时报告错误，并在 grpc 调用 ResolveNow 时重试
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	t, err := parseTarget(target.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := &nacosResolver{sub: b.sub, target: t, cc: cc}
+	r.subscribe()
+	return r, nil
+}
+
+// ResolveNow 实现 resolver.Resolver，实例变化由订阅推送，只在订阅失败时重试
+func (r *nacosResolver) ResolveNow(resolver.ResolveNowOptions) {
+	r.subscribe()
+}
+
+// Close 实现 resolver.Resolver
+func (r *nacosResolver) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+func (r *nacosResolver) subscribe() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || r.cancel != nil {
+		return
+	}
+
+	cancel, err := r.sub.Subscribe(r.target, r.update)
+	if err != nil {
+		logger.Warn("subscribe service failed", "service", r.target.Service, "group", r.target.Group, "error", err)
+		r.cc.ReportError(err)
+		return
+	}
+	r.cancel = cancel
+}
+
+func (r *nacosResolver) update(instances []model.Instance) {
+	if len(instances) == 0 {
+		r.cc.ReportError(fmt.Errorf("%w: %s", nacosx.ErrNoInstance, r.target.Service))
+		return
+	}
+
+	addrs := make([]resolver.Address, 0, len(instances))
+	for _, inst := range instances {
+		addrs = append(addrs, resolver.Address{
+			Addr:       nacosx.InstanceAddr(inst),
+			Attributes: attributes.New(weightKey, inst.Weight).WithValue(metadataKey, metadata(maps.Clone(inst.Metadata))),
+		})
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		logger.Warn("update resolver state failed", "service", r.target.Service, "error", err)
+	}
+}
+
+// parseTarget 解析 nacos://[group]/service?clusters=a,b&metadata.key=value
+func parseTarget(u url.URL) (nacosx.Target, error) {
+	t := nacosx.Target{
+		Service: strings.TrimPrefix(u.Path, "/"),
+		Group:   u.Host,
+	}
+	if t.Service == "" || strings.Contains(t.Service, "/") {
+		return t, errors.New("grpcx: invalid nacos target, expect nacos://[group]/service")
+	}
+
+	query := u.Query()
+	if clusters := query.Get("clusters"); clusters != "" {
+		t.Clusters = strings.Split(clusters, ",")
+	}
+	for k := range query {
+		if name, ok := strings.CutPrefix(k, metadataPrefix); ok {
+			if t.Metadata == nil {
+				t.Metadata = make(map[string]string)
+			}
+			t.Metadata[name] = query.Get(k)
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,184 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+type fakeSubscriber struct {
+	mu        sync.Mutex
+	err       error
+	instances []model.Instance
+	targets   []nacosx.Target
+	subs      map[int]func([]model.Instance)
+	nextID    int
+}
+
+func newFakeSubscriber(instances ...model.Instance) *fakeSubscriber {
+	return &fakeSubscriber{instances: instances, subs: make(map[int]func([]model.Instance))}
+}
+
+func (f *fakeSubscriber) Subscribe(t nacosx.Target, fn func([]model.Instance)) (func(), error) {
+	f.mu.Lock()
+	f.targets = append(f.targets, t)
+	if f.err != nil {
+		defer f.mu.Unlock()
+		return nil, f.err
+	}
+	id := f.nextID
+	f.nextID++
+	f.subs[id] = fn
+	instances := f.instances
+	f.mu.Unlock()
+
+	fn(instances)
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, id)
+	}, nil
+}
+
+func (f *fakeSubscriber) set(instances ...model.Instance) {
+	f.mu.Lock()
+	f.instances = instances
+	var subs []func([]model.Instance)
+	for _, fn := range f.subs {
+		subs = append(subs, fn)
+	}
+	f.mu.Unlock()
+	for _, fn := range subs {
+		fn(instances)
+	}
+}
+
+// fakeClientConn 记录 resolver 推送的状态
+type fakeClientConn struct {
+	resolver.ClientConn
+
+	mu     sync.Mutex
+	states []resolver.State
+	errs   []error
+}
+
+func (c *fakeClientConn) UpdateState(s resolver.State) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states = append(c.states, s)
+	return nil
+}
+
+func (c *fakeClientConn) ReportError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+func testTarget(t *testing.T, raw string) resolver.Target {
+	u, err := url.Parse(raw)
+	assert.Nil(t, err)
+	return resolver.Target{URL: *u}
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := parseTarget(testTarget(t, "nacos://SHARED/order?clusters=a,b&metadata.zone=hz&metadata.version=v2").URL)
+	assert.Nil(t, err)
+	assert.Equal(t, nacosx.Target{
+		Service:  "order",
+		Group:    "SHARED",
+		Clusters: []string{"a", "b"},
+		Metadata: map[string]string{"zone": "hz", "version": "v2"},
+	}, target)
+
+	target, err = parseTarget(testTarget(t, "nacos:///order").URL)
+	assert.Nil(t, err)
+	assert.Equal(t, nacosx.Target{Service: "order"}, target)
+
+	_, err = parseTarget(testTarget(t, "nacos:///").URL)
+	assert.NotNil(t, err)
+}
+
+func TestResolverUpdates(t *testing.T) {
+	sub := newFakeSubscriber(model.Instance{Ip: "10.0.0.1", Port: 9000, Weight: 5, Metadata: map[string]string{"zone": "hz"}})
+	cc := &fakeClientConn{}
+	r, err := NewBuilder(sub).Build(testTarget(t, "nacos:///order"), cc, resolver.BuildOptions{})
+	assert.Nil(t, err)
+
+	assert.Len(t, cc.states, 1)
+	addr := cc.states[0].Addresses[0]
+	assert.Equal(t, "10.0.0.1:9000", addr.Addr)
+	// 不设置 ServerName，TLS 校验使用 target 的 authority
+	assert.Empty(t, addr.ServerName)
+	assert.Equal(t, 5.0, Weight(addr))
+	assert.Equal(t, map[string]string{"zone": "hz"}, Metadata(addr))
+
+	sub.set(model.Instance{Ip: "10.0.0.1", Port: 9000, Weight: 5}, model.Instance{Ip: "10.0.0.2", Port: 9000, Weight: 1})
+	assert.Len(t, cc.states, 2)
+	assert.Len(t, cc.states[1].Addresses, 2)
+
+	sub.set()
+	assert.ErrorIs(t, cc.errs[0], nacosx.ErrNoInstance)
+
+	r.Close()
+	sub.set(model.Instance{Ip: "10.0.0.3", Port: 9000, Weight: 1})
+	assert.Len(t, cc.states, 2)
+}
+
+func TestResolverRetrySubscribe(t *testing.T) {
+	sub := newFakeSubscriber(model.Instance{Ip: "10.0.0.1", Port: 9000, Weight: 1})
+	sub.err = errors.New("instance list is empty")
+	cc := &fakeClientConn{}
+	r, err := NewBuilder(sub).Build(testTarget(t, "nacos:///order"), cc, resolver.BuildOptions{})
+	assert.Nil(t, err)
+	defer r.Close()
+	assert.Len(t, cc.errs, 1)
+	assert.Empty(t, cc.states)
+
+	sub.mu.Lock()
+	sub.err = nil
+	sub.mu.Unlock()
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	assert.Len(t, cc.states, 1)
+	// 已订阅时不再重复订阅
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	assert.Len(t, sub.targets, 2)
+}
+
+func TestResolverDial(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	addr := lis.Addr().(*net.TCPAddr)
+	sub := newFakeSubscriber(model.Instance{Ip: "127.0.0.1", Port: uint64(addr.Port), Weight: 1})
+	conn, err := grpc.NewClient("nacos:///health",
+		grpc.WithResolvers(NewBuilder(sub)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+}
@@ -0,0 +1,270 @@
+package logx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/betacats/go-core/utils/envx"
+)
+
+const (
+	// EncodingJson 每行一条 JSON 日志，适用于日志采集
+	EncodingJson = "json"
+	// EncodingConsole key=value 格式，适用于本地开发
+	EncodingConsole = "console"
+
+	moduleKey  = "module"
+	envKey     = "env"
+	traceIDKey = "trace_id"
+	spanIDKey  = "span_id"
+)
+
+type (
+	// Config 日志配置，可直接嵌入服务配置中并通过 nacosx 加载，
+	// Level 和 Modules 可在运行时通过 Apply 修改
+	Config struct {
+		Level     string            `json:",default=info,options=[debug,info,warn,error]"`
+		Encoding  string            `json:",optional,options=[json,console]"` // 为空时开发环境使用 console，其他环境使用 json
+		AddSource bool              `json:",optional"`
+		Modules   map[string]string `json:",optional"` // 按模块覆盖日志级别，如 kafkax: debug
+	}
+
+	// Option 自定义 Init 的选项
+	Option func(opt *options)
+
+	options struct {
+		writer io.Writer
+	}
+
+	// levels 当前生效的日志级别，整体替换保证读取时无锁
+	levels struct {
+		level   slog.Level
+		modules map[string]slog.Level
+	}
+
+	// base 底层 handler，Encoding 为空时在输出时按当前环境选择格式
+	base struct {
+		encoding string
+		json     slog.Handler
+		console  slog.Handler
+	}
+)
+
+var (
+	// root 当前的底层 handler，Init 时替换，已创建的模块 logger 随之生效
+	root    atomic.Pointer[base]
+	current atomic.Pointer[levels]
+
+	mu     sync.Mutex
+	config Config
+)
+
+func init() {
+	root.Store(newBase(Config{}, os.Stdout))
+	current.Store(&levels{level: slog.LevelInfo})
+	config = Config{Level: "info"}
+}
+
+// WithWriter 指定日志输出，默认为 os.Stdout
+func WithWriter(w io.Writer) Option {
+	return func(opt *options) {
+		opt.writer = w
+	}
+}
+
+// Init 按配置初始化日志，并将 slog 和标准库 log 的默认输出指向 logx
+func Init(c Config, opts ...Option) error {
+	opt := options{writer: os.Stdout}
+	for _, o := range opts {
+		o(&opt)
+	}
+	if c.Encoding != "" && c.Encoding != EncodingJson && c.Encoding != EncodingConsole {
+		return fmt.Errorf("logx: unknown encoding %q", c.Encoding)
+	}
+	if err := Apply(c); err != nil {
+		return err
+	}
+
+	root.Store(newBase(c, opt.writer))
+	slog.SetDefault(Default())
+	return nil
+}
+
+// Apply 运行时修改全局和各模块的日志级别，未出现在 Modules 中的模块恢复为全局级别
+func Apply(c Config) error {
+	lv, err := parseLevel(c.Level)
+	if err != nil {
+		return err
+	}
+	modules := make(map[string]slog.Level, len(c.Modules))
+	for name, level := range c.Modules {
+		if modules[name], err = parseLevel(level); err != nil {
+			return fmt.Errorf("logx: module %s: %w", name, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	config.Level = strings.ToLower(c.Level)
+	if config.Level == "" {
+		config.Level = "info"
+	}
+	config.Modules = make(map[string]string, len(c.Modules))
+	for name, level := range c.Modules {
+		config.Modules[name] = strings.ToLower(level)
+	}
+	current.Store(&levels{level: lv, modules: modules})
+	return nil
+}
+
+// SetLevel 运行时修改单个模块的日志级别，module 为空时修改全局级别，level 为空时删除模块的覆盖
+func SetLevel(module, level string) error {
+	c := Levels()
+	switch {
+	case module == "":
+		c.Level = level
+	case level == "":
+		delete(c.Modules, module)
+	default:
+		c.Modules[module] = level
+	}
+	return Apply(c)
+}
+
+// Levels 返回当前生效的日志级别配置
+func Levels() Config {
+	mu.Lock()
+	defer mu.Unlock()
+	c := config
+	c.Modules = make(map[string]string, len(config.Modules))
+	for name, level := range config.Modules {
+		c.Modules[name] = level
+	}
+	return c
+}
+
+// Default 返回不属于任何模块的 logger
+func Default() *slog.Logger {
+	return slog.New(&handler{})
+}
+
+// Module 返回属于 name 模块的 logger，日志带有 module 字段，级别受 Modules 覆盖控制。
+// 可在包级变量中提前创建，Init 之后自动使用新的输出格式
+func Module(name string) *slog.Logger {
+	return slog.New(&handler{module: name}).With(moduleKey, name)
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	var lv slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := lv.UnmarshalText([]byte(s)); err != nil {
+		return lv, fmt.Errorf("logx: %w", err)
+	}
+	return lv, nil
+}
+
+func newBase(c Config, w io.Writer) *base {
+	// 级别由 handler.Enabled 控制，底层 handler 输出所有级别
+	opts := &slog.HandlerOptions{AddSource: c.AddSource, Level: slog.LevelDebug - 4}
+	return &base{
+		encoding: c.Encoding,
+		json:     slog.NewJSONHandler(w, opts),
+		console:  slog.NewTextHandler(w, opts),
+	}
+}
+
+// handler 返回带有 env 字段的底层 handler。环境在输出时解析，
+// 导入 logx 的包初始化时 main 可能还未通过 envx 设置环境
+func (b *base) handler(env string) slog.Handler {
+	encoding := b.encoding
+	if encoding == "" {
+		encoding = EncodingJson
+		if env == envx.EnvDev {
+			encoding = EncodingConsole
+		}
+	}
+
+	h := b.json
+	if encoding == EncodingConsole {
+		h = b.console
+	}
+	return h.WithAttrs([]slog.Attr{slog.String(envKey, env)})
+}
+
+type (
+	// handler 按模块过滤级别、注入 trace 信息，并在输出时转发到当前的 root handler
+	handler struct {
+		module string
+		ops    []func(slog.Handler) slog.Handler
+		cache  atomic.Pointer[derived]
+	}
+
+	// derived 在 root handler 上应用 With/WithGroup 后的结果，root 或环境变化时重新构建
+	derived struct {
+		base *base
+		env  string
+		h    slog.Handler
+	}
+)
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	lv := current.Load()
+	if ml, ok := lv.modules[h.module]; ok && h.module != "" {
+		return level >= ml
+	}
+	return level >= lv.level
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String(traceIDKey, sc.TraceID().String()),
+			slog.String(spanIDKey, sc.SpanID().String()),
+		)
+	}
+
+	return h.inner().Handle(ctx, r)
+}
+
+// inner 返回缓存的底层 handler，Init 替换 root 或环境变化后重新构建
+func (h *handler) inner() slog.Handler {
+	b, env := root.Load(), envx.ENV()
+	if d := h.cache.Load(); d != nil && d.base == b && d.env == env {
+		return d.h
+	}
+
+	inner := b.handler(env)
+	for _, op := range h.ops {
+		inner = op(inner)
+	}
+	h.cache.Store(&derived{base: b, env: env, h: inner})
+	return inner
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler {
+		return inner.WithAttrs(attrs)
+	})
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler {
+		return inner.WithGroup(name)
+	})
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{module: h.module, ops: append(ops, op)}
+}
@@ -0,0 +1,154 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/betacats/go-core/utils/envx"
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+// setup 初始化输出到 buffer 的日志，并在测试结束后恢复默认配置
+func setup(t *testing.T, c Config) *bytes.Buffer {
+	var buf bytes.Buffer
+	assert.Nil(t, Init(c, WithWriter(&buf)))
+	t.Cleanup(func() {
+		_ = Init(Config{})
+	})
+	return &buf
+}
+
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var res []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		assert.Nil(t, jsonx.UnmarshalFromString(line, &m))
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestModuleLevels(t *testing.T) {
+	logger := Module("kafkax")
+	buf := setup(t, Config{Level: "info", Encoding: EncodingJson, Modules: map[string]string{"kafkax": "warn"}})
+
+	logger.Info("dropped")
+	logger.Warn("kept", "topic", "orders")
+	Default().Info("default")
+
+	res := lines(t, buf)
+	assert.Len(t, res, 2)
+	assert.Equal(t, "kept", res[0]["msg"])
+	assert.Equal(t, "kafkax", res[0]["module"])
+	assert.Equal(t, "orders", res[0]["topic"])
+	assert.Equal(t, envx.ENV(), res[0]["env"])
+	assert.Equal(t, "default", res[1]["msg"])
+
+	// 运行时调整模块级别
+	buf.Reset()
+	assert.Nil(t, SetLevel("kafkax", "debug"))
+	logger.Debug("debug")
+	assert.Len(t, lines(t, buf), 1)
+	assert.Equal(t, "debug", Levels().Modules["kafkax"])
+
+	buf.Reset()
+	assert.Nil(t, SetLevel("kafkax", ""))
+	logger.Debug("debug")
+	logger.Info("info")
+	assert.Len(t, lines(t, buf), 1)
+
+	assert.NotNil(t, SetLevel("kafkax", "verbose"))
+	assert.NotNil(t, Init(Config{Encoding: "xml"}))
+}
+
+func TestTraceCorrelation(t *testing.T) {
+	buf := setup(t, Config{Encoding: EncodingJson})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	Module("app").With("name", "demo").InfoContext(ctx, "traced")
+	Module("app").Info("untraced")
+
+	res := lines(t, buf)
+	assert.Len(t, res, 2)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", res[0]["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", res[0]["span_id"])
+	assert.Equal(t, "demo", res[0]["name"])
+	assert.NotContains(t, res[1], "trace_id")
+}
+
+func TestConsoleEncoding(t *testing.T) {
+	buf := setup(t, Config{Encoding: EncodingConsole})
+	Module("closes").Info("Close http ...")
+	assert.Contains(t, buf.String(), `msg="Close http ..."`)
+	assert.Contains(t, buf.String(), "module=closes")
+}
+
+func TestEnvResolvedLazily(t *testing.T) {
+	logger := Module("app")
+	buf := setup(t, Config{})
+
+	// 初始化之后设置的环境同样生效，Encoding 为空时按环境选择格式
+	envx.SetForTest(t, envx.EnvProd)
+	logger.Info("prod")
+	res := lines(t, buf)
+	assert.Len(t, res, 1)
+	assert.Equal(t, envx.EnvProd, res[0]["env"])
+
+	buf.Reset()
+	envx.SetForTest(t, envx.EnvDev)
+	logger.Info("dev")
+	assert.Contains(t, buf.String(), "env=dev")
+}
+
+func TestHandlerCache(t *testing.T) {
+	logger := Module("app").With("name", "demo")
+	buf := setup(t, Config{Encoding: EncodingJson})
+	logger.Info("first")
+	logger.Info("second")
+	h := logger.Handler().(*handler)
+	d := h.cache.Load()
+	assert.NotNil(t, d)
+
+	// 多条日志复用同一个底层 handler
+	logger.Info("third")
+	assert.Same(t, d, h.cache.Load())
+	assert.Len(t, lines(t, buf), 3)
+
+	// Init 后重新构建，并输出到新的 writer
+	buf2 := setup(t, Config{Encoding: EncodingJson})
+	logger.Info("after init")
+	assert.NotSame(t, d, h.cache.Load())
+	res := lines(t, buf2)
+	assert.Len(t, res, 1)
+	assert.Equal(t, "demo", res[0]["name"])
+	assert.Equal(t, "app", res[0]["module"])
+}
+
+func BenchmarkModuleLogger(b *testing.B) {
+	assert.Nil(b, Init(Config{Encoding: EncodingJson}, WithWriter(io.Discard)))
+	b.Cleanup(func() {
+		_ = Init(Config{})
+	})
+	logger := Module("kafkax").With("topic", "orders")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("message", "offset", i)
+	}
+}
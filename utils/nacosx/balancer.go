@@ -0,0 +1,180 @@
+package nacosx
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+)
+
+// virtualNodes 一致性哈希中最小权重实例的虚拟节点数
+const virtualNodes = 100
+
+type (
+	// balancer 从一组实例中选择一个，实例列表变化时重新创建
+	balancer interface {
+		pick(o pickOptions) (model.Instance, bool)
+	}
+
+	// roundRobin 平滑加权轮询
+	roundRobin struct {
+		mu        sync.Mutex
+		instances []model.Instance
+		current   []float64
+		total     float64
+	}
+
+	// weightedRandom 加权随机
+	weightedRandom struct {
+		instances []model.Instance
+	}
+
+	// consistentHash 带虚拟节点的一致性哈希，虚拟节点数与权重成正比
+	consistentHash struct {
+		instances []model.Instance
+		ring      []uint64
+		nodes     map[uint64]int
+		fallback  balancer
+	}
+)
+
+// InstanceAddr 返回实例的 host:port
+func InstanceAddr(inst model.Instance) string {
+	return net.JoinHostPort(inst.Ip, strconv.FormatUint(inst.Port, 10))
+}
+
+func newBalancer(strategy Strategy, instances []model.Instance) balancer {
+	switch strategy {
+	case StrategyRandom:
+		return &weightedRandom{instances: instances}
+	case StrategyConsistentHash:
+		return newConsistentHash(instances)
+	default:
+		return newRoundRobin(instances)
+	}
+}
+
+func newRoundRobin(instances []model.Instance) *roundRobin {
+	b := &roundRobin{instances: instances, current: make([]float64, len(instances))}
+	for _, inst := range instances {
+		b.total += inst.Weight
+	}
+	return b
+}
+
+func (b *roundRobin) pick(o pickOptions) (model.Instance, bool) {
+	// 排除部分实例时轮询状态不再适用，在剩余实例中加权随机
+	if len(o.exclude) > 0 {
+		return randomPick(b.instances, o.exclude)
+	}
+	if len(b.instances) == 0 {
+		return model.Instance{}, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	best := 0
+	for i, inst := range b.instances {
+		b.current[i] += inst.Weight
+		if b.current[i] > b.current[best] {
+			best = i
+		}
+	}
+	b.current[best] -= b.total
+	return b.instances[best], true
+}
+
+func (b *weightedRandom) pick(o pickOptions) (model.Instance, bool) {
+	return randomPick(b.instances, o.exclude)
+}
+
+// randomPick 在未排除的实例中加权随机选择
+func randomPick(instances []model.Instance, exclude map[string]bool) (model.Instance, bool) {
+	var total float64
+	for _, inst := range instances {
+		if !exclude[InstanceAddr(inst)] {
+			total += inst.Weight
+		}
+	}
+	if total <= 0 {
+		return model.Instance{}, false
+	}
+
+	n := rand.Float64() * total
+	var last model.Instance
+	for _, inst := range instances {
+		if exclude[InstanceAddr(inst)] {
+			continue
+		}
+		if n -= inst.Weight; n < 0 {
+			return inst, true
+		}
+		last = inst
+	}
+	// 浮点误差
+	return last, true
+}
+
+func newConsistentHash(instances []model.Instance) *consistentHash {
+	b := &consistentHash{
+		instances: instances,
+		nodes:     make(map[uint64]int),
+		fallback:  &weightedRandom{instances: instances},
+	}
+	minWeight := math.MaxFloat64
+	for _, inst := range instances {
+		minWeight = math.Min(minWeight, inst.Weight)
+	}
+	for i, inst := range instances {
+		addr := InstanceAddr(inst)
+		replicas := int(math.Round(inst.Weight / minWeight * virtualNodes))
+		for j := 0; j < replicas; j++ {
+			h := hashKey(addr + "#" + strconv.Itoa(j))
+			if _, ok := b.nodes[h]; ok {
+				continue
+			}
+			b.nodes[h] = i
+			b.ring = append(b.ring, h)
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool { return b.ring[i] < b.ring[j] })
+	return b
+}
+
+func (b *consistentHash) pick(o pickOptions) (model.Instance, bool) {
+	if o.hashKey == "" {
+		return b.fallback.pick(o)
+	}
+	if len(b.ring) == 0 {
+		return model.Instance{}, false
+	}
+
+	h := hashKey(o.hashKey)
+	start := sort.Search(len(b.ring), func(i int) bool { return b.ring[i] >= h })
+	// 顺时针找到第一个未排除的实例
+	for i := 0; i < len(b.ring); i++ {
+		inst := b.instances[b.nodes[b.ring[(start+i)%len(b.ring)]]]
+		if !o.exclude[InstanceAddr(inst)] {
+			return inst, true
+		}
+	}
+	return model.Instance{}, false
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	// fnv 对相近的字符串分布不够均匀，再做一次混合
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
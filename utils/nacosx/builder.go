@@ -1,30 +1,64 @@
 package nacosx
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
 )
 
+// Mode 指定 Builder 创建的客户端
+type Mode uint8
+
+const (
+	// ModeConfig 只创建配置客户端，不注册服务
+	ModeConfig Mode = 1 << iota
+	// ModeNaming 只创建命名客户端
+	ModeNaming
+	// ModeAll 同时创建配置和命名客户端，默认值
+	ModeAll = ModeConfig | ModeNaming
+)
+
+// TLSConfig 与服务端通信的 TLS 配置
+type TLSConfig struct {
+	CaFile             string // 校验服务端证书的 CA 文件
+	CertFile           string // 客户端证书
+	KeyFile            string // 客户端私钥
+	TrustAll           bool   // 不校验服务端证书
+	ServerNameOverride string // 覆盖校验的服务端名称，仅用于测试
+}
+
 type config struct {
 	// 服务器配置
-	IPAddr string // 服务地址
-	Port   uint64 // 服务端口
-	Path   string // 上下文路径
+	IPAddr              string     // 服务地址
+	Port                uint64     // 服务端口
+	Path                string     // 上下文路径
+	Servers             []string   // 服务地址列表，格式为 host:port，不为空时忽略 IPAddr 和 Port
+	Endpoint            string     // 地址服务器，设置后从地址服务器获取服务地址列表
+	EndpointContextPath string     // 地址服务器上下文路径
+	TLS                 *TLSConfig // TLS 配置，为空时不启用
 
 	// 客户端配置
-	NamespaceID  string // 命名空间ID
-	Username     string // 用户名
-	Password     string // 密码
-	TimeoutMs    uint64 // 超时时间(ms)
-	LogDir       string // 日志目录
-	CacheDir     string // 缓存目录
-	LogLevel     string // 日志级别
-	NotLoadCache bool   // 启动时是否不加载缓存
-	DataID       string // 数据id
+	NamespaceID  string         // 命名空间ID
+	Username     string         // 用户名
+	Password     string         // 密码
+	AccessKey    string         // 阿里云 MSE 等使用的 AccessKey
+	SecretKey    string         // 阿里云 MSE 等使用的 SecretKey
+	Mode         Mode           // 创建的客户端
+	TimeoutMs    uint64         // 超时时间(ms)
+	LogDir       string         // 日志目录
+	CacheDir     string         // 缓存目录
+	LogLevel     string         // 日志级别
+	NotLoadCache bool           // 启动时是否不加载缓存
+	DataID       string         // 数据id
+	Sources      []ConfigSource // 按顺序合并的配置源，为空时只使用 DataID
+	SnapshotDir  string         // 配置快照目录，默认为空，不保存快照
 
 	// 服务注册配置
 	ServiceName    string // 服务名称
@@ -54,6 +88,7 @@ func NewBuilder() *Builder {
 			NamespaceID:    "",
 			ServiceGroup:   "DEFAULT_GROUP",
 			ServiceCluster: "DEFAULT",
+			Mode:           ModeAll,
 		},
 	}
 }
@@ -65,6 +100,38 @@ func (b *Builder) WithServerAddr(ip string, port uint64) *Builder {
 	return b
 }
 
+// WithServers 设置多个服务地址，格式为 host:port，省略端口时为 8848
+func (b *Builder) WithServers(addrs ...string) *Builder {
+	b.config.Servers = append([]string(nil), addrs...)
+	return b
+}
+
+// WithEndpoint 设置地址服务器，客户端从地址服务器获取并定期刷新服务地址列表
+func (b *Builder) WithEndpoint(endpoint, contextPath string) *Builder {
+	b.config.Endpoint = endpoint
+	b.config.EndpointContextPath = contextPath
+	return b
+}
+
+// WithTLS 启用 TLS
+func (b *Builder) WithTLS(c TLSConfig) *Builder {
+	b.config.TLS = &c
+	return b
+}
+
+// WithAccessKey 设置 AccessKey 认证信息
+func (b *Builder) WithAccessKey(accessKey, secretKey string) *Builder {
+	b.config.AccessKey = accessKey
+	b.config.SecretKey = secretKey
+	return b
+}
+
+// WithMode 设置创建的客户端，如只加载配置时使用 ModeConfig，避免注册服务
+func (b *Builder) WithMode(mode Mode) *Builder {
+	b.config.Mode = mode
+	return b
+}
+
 // WithContextPath 设置上下文路径
 func (b *Builder) WithContextPath(path string) *Builder {
 	b.config.Path = path
@@ -83,6 +150,12 @@ func (b *Builder) WithDataID(dataID string) *Builder {
 	return b
 }
 
+// WithConfigSources 设置按顺序合并的配置源，后面的覆盖前面的，如公共配置在前、服务配置在后
+func (b *Builder) WithConfigSources(sources ...ConfigSource) *Builder {
+	b.config.Sources = append([]ConfigSource(nil), sources...)
+	return b
+}
+
 // WithAuth 设置认证信息
 func (b *Builder) WithAuth(username, password string) *Builder {
 	b.config.Username = username
@@ -110,6 +183,13 @@ func (b *Builder) WithCacheConfig(cacheDir string, notLoadCache bool) *Builder {
 	return b
 }
 
+// WithSnapshotDir 开启配置快照，服务端不可用时使用最近一次成功加载的快照。
+// 快照保存原始配置内容，可能包含明文密钥，目录应位于只有服务自身可访问的位置
+func (b *Builder) WithSnapshotDir(dir string) *Builder {
+	b.config.SnapshotDir = dir
+	return b
+}
+
 // WithServiceInfo 设置服务注册信息
 func (b *Builder) WithServiceInfo(name, ip string, port uint64) *Builder {
 	b.config.ServiceName = name
@@ -130,21 +210,93 @@ func (b *Builder) WithServiceCluster(cluster string) *Builder {
 	return b
 }
 
-// Execute 生成Nacosx实例
+// Execute 生成Nacosx实例，失败时 panic
 func (b *Builder) Execute() *Nacosx {
+	nacosx, err := b.ExecuteE()
+	if err != nil {
+		panic(err)
+	}
+	return nacosx
+}
+
+// ExecuteE 生成Nacosx实例，按 Mode 创建客户端，提供了服务信息且创建了命名客户端时注册服务
+func (b *Builder) ExecuteE() (*Nacosx, error) {
+	if b.config.Mode&ModeAll == 0 {
+		return nil, errors.New("nacosx: no client enabled")
+	}
+	serverConfigs, err := b.serverConfigs()
+	if err != nil {
+		return nil, err
+	}
+	param := vo.NacosClientParam{
+		ClientConfig:  b.clientConfig(),
+		ServerConfigs: serverConfigs,
+	}
+
 	nacosx := &Nacosx{config: b.config}
 
-	// 创建服务器配置
-	serverConfigs := []constant.ServerConfig{
-		*constant.NewServerConfig(
-			b.config.IPAddr,
-			b.config.Port,
-			constant.WithContextPath(b.config.Path),
-		),
+	// 初始化配置客户端
+	if b.config.Mode&ModeConfig != 0 {
+		configClient, err := clients.NewConfigClient(param)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create config client: %w", err)
+		}
+		nacosx.configClient = configClient
+	}
+
+	// 初始化命名客户端
+	if b.config.Mode&ModeNaming != 0 {
+		namingClient, err := clients.NewNamingClient(param)
+		if err != nil {
+			nacosx.Close()
+			return nil, fmt.Errorf("failed to create naming client: %w", err)
+		}
+		nacosx.namingClient = namingClient
+	}
+
+	// 如果提供了服务信息，则注册服务
+	if nacosx.namingClient != nil && b.config.ServiceName != "" && b.config.ServiceIP != "" && b.config.ServicePort > 0 {
+		if err := nacosx.RegisterService(); err != nil {
+			nacosx.Close()
+			return nil, fmt.Errorf("failed to register service: %w", err)
+		}
 	}
 
-	// 创建客户端配置
-	clientConfig := &constant.ClientConfig{
+	return nacosx, nil
+}
+
+// serverConfigs 返回服务端配置，使用地址服务器时为空
+func (b *Builder) serverConfigs() ([]constant.ServerConfig, error) {
+	if b.config.Endpoint != "" {
+		return nil, nil
+	}
+
+	scheme := "http"
+	if b.config.TLS != nil {
+		scheme = "https"
+	}
+	if len(b.config.Servers) == 0 {
+		return []constant.ServerConfig{
+			*constant.NewServerConfig(b.config.IPAddr, b.config.Port,
+				constant.WithContextPath(b.config.Path), constant.WithScheme(scheme)),
+		}, nil
+	}
+
+	res := make([]constant.ServerConfig, 0, len(b.config.Servers))
+	for _, addr := range b.config.Servers {
+		host, port, err := splitServerAddr(addr)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *constant.NewServerConfig(host, port,
+			constant.WithContextPath(b.config.Path), constant.WithScheme(scheme)))
+	}
+	return res, nil
+}
+
+// clientConfig 返回客户端配置
+func (b *Builder) clientConfig() *constant.ClientConfig {
+	c := &constant.ClientConfig{
 		NamespaceId:         b.config.NamespaceID,
 		TimeoutMs:           b.config.TimeoutMs,
 		NotLoadCacheAtStart: b.config.NotLoadCache,
@@ -153,34 +305,42 @@ func (b *Builder) Execute() *Nacosx {
 		LogLevel:            b.config.LogLevel,
 		Username:            b.config.Username,
 		Password:            b.config.Password,
+		AccessKey:           b.config.AccessKey,
+		SecretKey:           b.config.SecretKey,
+		Endpoint:            b.config.Endpoint,
+		EndpointContextPath: b.config.EndpointContextPath,
 	}
-
-	// 初始化命名客户端
-	namingClient, err := clients.NewNamingClient(vo.NacosClientParam{
-		ClientConfig:  clientConfig,
-		ServerConfigs: serverConfigs,
-	})
-	if err != nil {
-		panic(fmt.Errorf("failed to create naming client: %w", err))
+	if tls := b.config.TLS; tls != nil {
+		c.TLSCfg = constant.TLSConfig{
+			Appointed:          true,
+			Enable:             true,
+			TrustAll:           tls.TrustAll,
+			CaFile:             tls.CaFile,
+			CertFile:           tls.CertFile,
+			KeyFile:            tls.KeyFile,
+			ServerNameOverride: tls.ServerNameOverride,
+		}
 	}
-	nacosx.namingClient = namingClient
+	return c
+}
 
-	// 初始化配置客户端
-	configClient, err := clients.NewConfigClient(vo.NacosClientParam{
-		ClientConfig:  clientConfig,
-		ServerConfigs: serverConfigs,
-	})
-	if err != nil {
-		panic(fmt.Errorf("failed to create config client: %w", err))
+// splitServerAddr 解析 host:port，省略端口时为 8848
+func splitServerAddr(addr string) (string, uint64, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "", 0, errors.New("nacosx: empty server address")
 	}
-	nacosx.configClient = configClient
-
-	// 如果提供了服务信息，则注册服务
-	if b.config.ServiceName != "" && b.config.ServiceIP != "" && b.config.ServicePort > 0 {
-		if err := nacosx.RegisterService(); err != nil {
-			panic(fmt.Errorf("failed to register service: %w", err))
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		var addrErr *net.AddrError
+		if errors.As(err, &addrErr) && addrErr.Err == "missing port in address" {
+			return strings.Trim(addr, "[]"), 8848, nil
 		}
+		return "", 0, fmt.Errorf("nacosx: invalid server address %q: %w", addr, err)
 	}
-
-	return nacosx
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", 0, fmt.Errorf("nacosx: invalid server port in %q", addr)
+	}
+	return host, port, nil
 }
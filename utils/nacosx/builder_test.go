@@ -2,8 +2,12 @@ package nacosx
 
 import (
 	"fmt"
+	"net"
 	"testing"
 	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/stretchr/testify/assert"
 )
 
 //type ZeroConfig struct {
@@ -50,6 +54,13 @@ type AppConfig struct {
 }
 
 func TestNacosByAppConfig(t *testing.T) {
+	// 需要本地运行的 nacos 服务
+	conn, err := net.DialTimeout("tcp", "localhost:8848", time.Second)
+	if err != nil {
+		t.Skipf("nacos server not available: %v", err)
+	}
+	_ = conn.Close()
+
 	nacosx := NewBuilder().
 		WithServerAddr("localhost", 8848).
 		WithNamespace("public").
@@ -59,14 +70,74 @@ func TestNacosByAppConfig(t *testing.T) {
 	fmt.Println(nacosx.config.IPAddr)
 
 	var c AppConfig
-	nacosx.MustLoad(&c)
+	if err = nacosx.Load(&c); err != nil {
+		t.Skipf("load nacos config: %v", err)
+	}
 
 	fmt.Println("xxxxx", c.Name)
 
 	nacosx.ListenConfig(func(namespace, group, dataID, data string) {
-		nacosx.MustLoad(&c)
+		_ = nacosx.Load(&c)
 		fmt.Println("lister....c.Name...", c.Name)
 	})
 
 	time.Sleep(30 * time.Second)
 }
+
+func TestBuilderServerConfigs(t *testing.T) {
+	b := NewBuilder().WithServerAddr("10.0.0.1", 8849)
+	servers, err := b.serverConfigs()
+	assert.Nil(t, err)
+	assert.Len(t, servers, 1)
+	assert.Equal(t, "10.0.0.1", servers[0].IpAddr)
+	assert.Equal(t, uint64(8849), servers[0].Port)
+	assert.Equal(t, "http", servers[0].Scheme)
+
+	b.WithServers("10.0.0.1:8848", "nacos-2", "[::1]:9848").WithTLS(TLSConfig{CaFile: "ca.pem"})
+	servers, err = b.serverConfigs()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "nacos-2", "::1"}, []string{servers[0].IpAddr, servers[1].IpAddr, servers[2].IpAddr})
+	assert.Equal(t, []uint64{8848, 8848, 9848}, []uint64{servers[0].Port, servers[1].Port, servers[2].Port})
+	assert.Equal(t, "https", servers[2].Scheme)
+
+	_, err = NewBuilder().WithServers("nacos:http").serverConfigs()
+	assert.NotNil(t, err)
+	_, err = NewBuilder().WithServers("").ExecuteE()
+	assert.NotNil(t, err)
+
+	// 使用地址服务器时不设置服务地址
+	servers, err = NewBuilder().WithEndpoint("addr.example.com:8080", "nacos").serverConfigs()
+	assert.Nil(t, err)
+	assert.Empty(t, servers)
+}
+
+func TestBuilderClientConfig(t *testing.T) {
+	c := NewBuilder().
+		WithAccessKey("ak", "sk").
+		WithEndpoint("addr.example.com:8080", "nacos").
+		WithTLS(TLSConfig{CaFile: "ca.pem", TrustAll: true}).
+		clientConfig()
+	assert.Equal(t, "ak", c.AccessKey)
+	assert.Equal(t, "sk", c.SecretKey)
+	assert.Equal(t, "addr.example.com:8080", c.Endpoint)
+	assert.Equal(t, "nacos", c.EndpointContextPath)
+	assert.Equal(t, constant.TLSConfig{Appointed: true, Enable: true, TrustAll: true, CaFile: "ca.pem"}, c.TLSCfg)
+
+	assert.False(t, NewBuilder().clientConfig().TLSCfg.Enable)
+}
+
+func TestExecuteEMode(t *testing.T) {
+	_, err := NewBuilder().WithMode(0).ExecuteE()
+	assert.NotNil(t, err)
+
+	// 只有配置客户端时服务注册相关方法返回错误
+	n := newTestNacosx(t, newFakeConfigClient())
+	assert.ErrorIs(t, n.RegisterService(), ErrNamingClientDisabled)
+	_, err = n.GetServiceInstances()
+	assert.ErrorIs(t, err, ErrNamingClientDisabled)
+
+	n = &Nacosx{config: &config{DataID: "app.yaml"}}
+	var c watchConfig
+	assert.ErrorIs(t, n.Load(&c), ErrConfigClientDisabled)
+	assert.False(t, n.ServerHealthy())
+}
@@ -0,0 +1,82 @@
+// nacosx-encrypt 加密配置中的敏感值，输出可以直接写入 nacos 配置的 ENC(base64)。
+//
+// 密钥取自 -key-file，未指定时取 NACOSX_SECRET_KEY 或 NACOSX_SECRET_KEY_FILE 环境变量：
+//
+//	nacosx-encrypt -genkey > secret.key
+//	nacosx-encrypt -key-file secret.key 'my-password'
+//	echo -n 'my-password' | nacosx-encrypt -key-file secret.key
+//	nacosx-encrypt -key-file secret.key -d 'ENC(...)'
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+func main() {
+	var (
+		keyFile = flag.String("key-file", "", "file containing the base64 encoded AES key")
+		genKey  = flag.Bool("genkey", false, "generate a random base64 encoded 32 bytes key")
+		decrypt = flag.Bool("d", false, "decrypt an ENC(...) value instead of encrypting")
+	)
+	flag.Parse()
+
+	if err := run(*keyFile, *genKey, *decrypt, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(keyFile string, genKey, decrypt bool, args []string) error {
+	if genKey {
+		key, err := nacosx.GenerateSecretKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return nil
+	}
+
+	var (
+		key []byte
+		err error
+	)
+	if keyFile != "" {
+		key, err = nacosx.ReadSecretKeyFile(keyFile)
+	} else {
+		key, err = nacosx.SecretKey()
+	}
+	if err != nil {
+		return err
+	}
+
+	var value string
+	if len(args) > 0 {
+		value = strings.Join(args, " ")
+	} else {
+		// 从标准输入读取，避免明文留在 shell 历史中
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = strings.TrimRight(string(content), "\r\n")
+	}
+
+	var res string
+	if decrypt {
+		res, err = nacosx.Decrypt(key, value)
+	} else {
+		res, err = nacosx.Encrypt(key, value)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(res)
+	return nil
+}
@@ -58,8 +58,8 @@ func Load(file string, v any, opts ...Option) error {
 		return err
 	}
 
-	loader, ok := loaders[strings.ToLower(path.Ext(file))]
-	if !ok {
+	ext := strings.ToLower(path.Ext(file))
+	if _, ok := loaders[ext]; !ok {
 		return fmt.Errorf("unrecognized file type: %s", file)
 	}
 
@@ -67,6 +67,7 @@ func Load(file string, v any, opts ...Option) error {
 	for _, o := range opts {
 		o(&opt)
 	}
+	loader := opt.parser(ext)
 
 	if opt.env {
 		return loader([]byte(os.ExpandEnv(string(content))), v)
@@ -92,7 +93,6 @@ func LoadFromJsonBytes(content []byte, v any) error {
 	if err = jsonx.Unmarshal(content, &m); err != nil {
 		return err
 	}
-
 	lowerCaseKeyMap := toLowerCaseKeyMap(m, info)
 
 	return mapping.UnmarshalJsonMap(lowerCaseKeyMap, v, mapping.WithCanonicalKeyFunc(toLowerCase))
@@ -0,0 +1,70 @@
+package nacosx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ChangedPaths 返回 old 和 new 之间值不同的字段路径，如 Mysql.Host、Modules.kafkax。
+// 结构体和 map 递归比较，数组等其他类型整体比较
+func ChangedPaths(old, new any) []string {
+	var paths []string
+	diffValue(reflect.ValueOf(old), reflect.ValueOf(new), "", &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func diffValue(a, b reflect.Value, path string, paths *[]string) {
+	for a.IsValid() && (a.Kind() == reflect.Pointer || a.Kind() == reflect.Interface) && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.IsValid() && (b.Kind() == reflect.Pointer || b.Kind() == reflect.Interface) && !b.IsNil() {
+		b = b.Elem()
+	}
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() {
+		if a.IsValid() != b.IsValid() || (a.IsValid() && !reflect.DeepEqual(a.Interface(), b.Interface())) {
+			*paths = append(*paths, path)
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if !field.Anonymous {
+				name = join(path, name)
+			} else {
+				name = path
+			}
+			diffValue(a.Field(i), b.Field(i), name, paths)
+		}
+	case reflect.Map:
+		keys := make(map[any]reflect.Value)
+		for _, k := range a.MapKeys() {
+			keys[k.Interface()] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[k.Interface()] = k
+		}
+		for _, k := range keys {
+			diffValue(a.MapIndex(k), b.MapIndex(k), join(path, fmt.Sprint(k.Interface())), paths)
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
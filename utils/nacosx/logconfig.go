@@ -0,0 +1,38 @@
+package nacosx
+
+import (
+	"github.com/betacats/go-core/utils/logx"
+)
+
+// logConfig 服务配置中的日志配置段
+type logConfig struct {
+	Log logx.Config `json:",optional"`
+}
+
+// ApplyLogConfig 解析 yaml 配置中的 log 配置段并调用 logx.Apply 调整日志级别，
+// 服务已自行监听配置时，在回调中调用即可
+func ApplyLogConfig(content []byte) error {
+	var c logConfig
+	if err := LoadFromYamlBytes(content, &c); err != nil {
+		return err
+	}
+	return logx.Apply(c.Log)
+}
+
+// ListenLogConfig 加载并监听所有配置源合并后的 log 配置段，变化时运行时调整日志级别。
+// 通过 NewWatch 监听，与服务自身的配置监听共享 nacos 监听器
+func (s *Nacosx) ListenLogConfig(opts ...Option) error {
+	w, err := NewWatch[logConfig](s, opts...)
+	if err != nil {
+		return err
+	}
+	if err = logx.Apply(w.Load().Log); err != nil {
+		return err
+	}
+	w.Subscribe(func(_, c *logConfig, _ []string) {
+		if err := logx.Apply(c.Log); err != nil {
+			logger.Warn("failed to apply log config", "error", err)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,59 @@
+package nacosx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/utils/logx"
+)
+
+func TestApplyLogConfig(t *testing.T) {
+	t.Cleanup(func() { _ = logx.Apply(logx.Config{}) })
+
+	assert.Nil(t, ApplyLogConfig([]byte(`
+name: demo
+log:
+  level: warn
+  modules:
+    kafkax: debug
+`)))
+	c := logx.Levels()
+	assert.Equal(t, "warn", c.Level)
+	assert.Equal(t, map[string]string{"kafkax": "debug"}, c.Modules)
+
+	// 没有 log 配置段时恢复默认级别
+	assert.Nil(t, ApplyLogConfig([]byte("name: demo\n")))
+	c = logx.Levels()
+	assert.Equal(t, "info", c.Level)
+	assert.Empty(t, c.Modules)
+
+	assert.NotNil(t, ApplyLogConfig([]byte("log:\n  level: verbose\n")))
+}
+
+func TestListenLogConfig(t *testing.T) {
+	t.Cleanup(func() { _ = logx.Apply(logx.Config{}) })
+
+	client := newFakeConfigClient()
+	client.publish("SHARED", "common.yaml", "log:\n  level: warn\n")
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 8080\nmysql:\n  host: db\n")
+	n := newTestNacosx(t, client)
+	n.config.Sources = []ConfigSource{{DataID: "common.yaml", Group: "SHARED"}, {DataID: "app.yaml"}}
+
+	w, err := NewWatch[watchConfig](n)
+	assert.Nil(t, err)
+	var pushed int
+	assert.Nil(t, n.ListenConfig(func(namespace, group, dataID, data string) {
+		pushed++
+	}))
+	assert.Nil(t, n.ListenLogConfig())
+	assert.Equal(t, "warn", logx.Levels().Level)
+
+	// 日志配置和服务配置共享同一个监听器，两边都能收到变化
+	client.publish("SHARED", "common.yaml", "log:\n  level: debug\n")
+	assert.Equal(t, "debug", logx.Levels().Level)
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 9090\nmysql:\n  host: db\nlog:\n  level: error\n")
+	assert.Equal(t, "error", logx.Levels().Level)
+	assert.Equal(t, 9090, w.Load().Port)
+	assert.Equal(t, 1, pushed)
+}
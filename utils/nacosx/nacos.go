@@ -1,25 +1,49 @@
 package nacosx
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"log"
+	"reflect"
+	"sync"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/model"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	"github.com/betacats/go-core/utils/logx"
+)
+
+var logger = logx.Module("nacosx")
+
+var (
+	// ErrConfigClientDisabled 构建时没有创建配置客户端
+	ErrConfigClientDisabled = errors.New("nacosx: config client disabled")
+	// ErrNamingClientDisabled 构建时没有创建命名客户端
+	ErrNamingClientDisabled = errors.New("nacosx: naming client disabled")
 )
 
 type Nacosx struct {
 	config       *config
 	namingClient naming_client.INamingClient
 	configClient config_client.IConfigClient
+
+	mu        sync.Mutex
+	listeners map[ConfigSource][]func(namespace, group, dataID, data string) // nacos 对同一个 dataId 只保留第一个监听器，由 Nacosx 统一分发
+
+	ctxOnce sync.Once
+	ctx     context.Context // Close 时取消，结束后台任务
+	cancel  context.CancelFunc
 }
 
 // 服务注册相关方法
 
 // RegisterService 注册服务到Nacos
 func (s *Nacosx) RegisterService() error {
+	if s.namingClient == nil {
+		return ErrNamingClientDisabled
+	}
 	success, err := s.namingClient.RegisterInstance(vo.RegisterInstanceParam{
 		Ip:          s.config.ServiceIP,
 		Port:        s.config.ServicePort,
@@ -41,6 +65,9 @@ func (s *Nacosx) RegisterService() error {
 
 // DeregisterService 从Nacos注销服务
 func (s *Nacosx) DeregisterService() error {
+	if s.namingClient == nil {
+		return ErrNamingClientDisabled
+	}
 	success, err := s.namingClient.DeregisterInstance(vo.DeregisterInstanceParam{
 		Ip:          s.config.ServiceIP,
 		Port:        s.config.ServicePort,
@@ -61,6 +88,9 @@ func (s *Nacosx) DeregisterService() error {
 
 // GetConfig 获取配置
 func (s *Nacosx) GetConfig() (string, error) {
+	if s.configClient == nil {
+		return "", ErrConfigClientDisabled
+	}
 	return s.configClient.GetConfig(vo.ConfigParam{
 		DataId: s.config.DataID,
 		Group:  s.config.ServiceGroup,
@@ -69,6 +99,9 @@ func (s *Nacosx) GetConfig() (string, error) {
 
 // PublishConfig 发布配置
 func (s *Nacosx) PublishConfig(content string) (bool, error) {
+	if s.configClient == nil {
+		return false, ErrConfigClientDisabled
+	}
 	return s.configClient.PublishConfig(vo.ConfigParam{
 		DataId:  s.config.DataID,
 		Group:   s.config.ServiceGroup,
@@ -78,23 +111,66 @@ func (s *Nacosx) PublishConfig(content string) (bool, error) {
 
 // DeleteConfig 删除配置
 func (s *Nacosx) DeleteConfig() (bool, error) {
+	if s.configClient == nil {
+		return false, ErrConfigClientDisabled
+	}
 	return s.configClient.DeleteConfig(vo.ConfigParam{
 		DataId: s.config.DataID,
 		Group:  s.config.ServiceGroup,
 	})
 }
 
-// ListenConfig 监听配置变化
+// ListenConfig 监听配置变化，与 NewWatch、ListenLogConfig 共享同一个 nacos 监听器
 func (s *Nacosx) ListenConfig(callback func(namespace, group, dataID, data string)) error {
-	return s.configClient.ListenConfig(vo.ConfigParam{
+	if s.configClient == nil {
+		return ErrConfigClientDisabled
+	}
+	return s.listen(ConfigSource{DataID: s.config.DataID, Group: s.config.ServiceGroup}, callback)
+}
+
+// ServerHealthy 返回 Nacos 服务端当前是否可用，只有配置客户端时通过查询当前 dataId 判断
+func (s *Nacosx) ServerHealthy() bool {
+	if s.namingClient != nil {
+		return s.namingClient.ServerHealthy()
+	}
+	if s.configClient == nil {
+		return false
+	}
+	_, err := s.configClient.SearchConfig(vo.SearchConfigParam{
+		Search:   "accurate",
 		DataId:   s.config.DataID,
 		Group:    s.config.ServiceGroup,
-		OnChange: callback,
+		PageNo:   1,
+		PageSize: 1,
+	})
+	return err == nil
+}
+
+// Close 停止后台任务并关闭已创建的客户端
+func (s *Nacosx) Close() {
+	s.context()
+	s.cancel()
+	if s.configClient != nil {
+		s.configClient.CloseClient()
+	}
+	if s.namingClient != nil {
+		s.namingClient.CloseClient()
+	}
+}
+
+// context 返回 Close 时取消的 context
+func (s *Nacosx) context() context.Context {
+	s.ctxOnce.Do(func() {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
 	})
+	return s.ctx
 }
 
-// GetServiceInstances 获取服务实例列表
+// GetServiceInstances 获取当前服务的健康实例列表，解析其他服务并负载均衡时使用 Resolver
 func (s *Nacosx) GetServiceInstances() ([]model.Instance, error) {
+	if s.namingClient == nil {
+		return nil, ErrNamingClientDisabled
+	}
 	return s.namingClient.SelectInstances(vo.SelectInstancesParam{
 		ServiceName: s.config.ServiceName,
 		GroupName:   s.config.ServiceGroup,
@@ -102,15 +178,37 @@ func (s *Nacosx) GetServiceInstances() ([]model.Instance, error) {
 	})
 }
 
-func (s *Nacosx) MustLoad(v interface{}) {
-	configContent, err := s.GetConfig()
+// Load 加载所有配置源并按顺序合并到 v，后面的配置源覆盖前面的。
+// 格式由 WithFormat 指定，否则取各 dataId 的扩展名，没有扩展名时按 yaml 解析。
+// 通过 Builder.WithSnapshotDir 开启快照时，解析成功后保存本地快照，服务端不可用时使用快照，服务端恢复后在后台刷新快照但不修改 v，
+// 需要在服务端恢复后自动更新时应使用 NewWatch
+func (s *Nacosx) Load(v any, opts ...Option) error {
+	var opt options
+	for _, o := range opts {
+		o(&opt)
+	}
+	set, err := s.fetchSources(opt)
 	if err != nil {
-		fmt.Printf("failed to get config from nacos: %v\n", err)
-		return
+		return err
 	}
-	if err = LoadFromYamlBytes([]byte(configContent), v); err != nil {
-		fmt.Printf("failed to load config: %v\n", err)
-		return
+	content, err := set.merged()
+	if err != nil {
+		return err
+	}
+	parse := opt.parser(".json")
+	if err = parse(content, v); err != nil {
+		return err
 	}
+	s.saveSnapshots(set)
+	s.refreshSnapshots(set, func(merged []byte) error {
+		return parse(merged, reflect.New(reflect.TypeOf(v).Elem()).Interface())
+	})
+	return nil
+}
 
+// MustLoad 加载所有配置源并合并到 v，失败时退出进程
+func (s *Nacosx) MustLoad(v any, opts ...Option) {
+	if err := s.Load(v, opts...); err != nil {
+		log.Fatalf("error: nacos config %s, %s", s.config.DataID, err.Error())
+	}
 }
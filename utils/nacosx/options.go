@@ -1,11 +1,17 @@
 package nacosx
 
+import "github.com/betacats/go-core/utils/envx"
+
 type (
 	// Option defines the method to customize the config options.
 	Option func(opt *options)
 
 	options struct {
-		env bool
+		env     bool
+		profile envx.Env
+		format  string
+		decrypt bool
+		key     []byte
 	}
 )
 
@@ -15,3 +21,34 @@ func UseEnv() Option {
 		opt.env = true
 	}
 }
+
+// WithProfile customizes the environment used by LoadEnvOverlay instead of envx.Current().
+func WithProfile(env envx.Env) Option {
+	return func(opt *options) {
+		opt.profile = env
+	}
+}
+
+// WithDecrypt customizes the config to decrypt values in the form of ENC(base64) with SecretKey,
+// values are loaded as is by default.
+func WithDecrypt() Option {
+	return func(opt *options) {
+		opt.decrypt = true
+	}
+}
+
+// WithSecretKey customizes the config to decrypt values in the form of ENC(base64) with key instead of SecretKey.
+func WithSecretKey(key []byte) Option {
+	return func(opt *options) {
+		opt.decrypt = true
+		opt.key = key
+	}
+}
+
+// WithFormat customizes the config format used by Nacosx.Load instead of the data ID extension,
+// json, yaml, yml and toml are acceptable.
+func WithFormat(format string) Option {
+	return func(opt *options) {
+		opt.format = format
+	}
+}
@@ -0,0 +1,102 @@
+package nacosx
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/betacats/go-core/utils/envx"
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+const (
+	// overlayBase 所有环境共用的基础配置文件名
+	overlayBase = "base"
+	// overlayLocal 本地覆盖配置文件名，不应提交到代码仓库
+	overlayLocal = "local"
+)
+
+// overlayExts 按顺序查找的配置文件扩展名
+var overlayExts = []string{".yaml", ".yml", ".json", ".toml"}
+
+// LoadEnvOverlay 从 dir 中依次加载 base、<env> 和 local 配置文件并深度合并到 v，后加载的覆盖先加载的。
+// 环境默认取 envx.Resolve()，严格模式下环境未知时返回错误，可通过 WithProfile 指定；base 必须存在，其余文件不存在时跳过。
+// 支持 .yaml、.yml、.json 和 .toml 扩展名
+func LoadEnvOverlay(dir string, v any, opts ...Option) error {
+	var opt options
+	for _, o := range opts {
+		o(&opt)
+	}
+	profile := opt.profile
+	if profile == "" {
+		var err error
+		if profile, err = envx.Resolve(); err != nil {
+			return err
+		}
+	}
+
+	merged := make(map[string]any)
+	for _, name := range []string{overlayBase, profile.String(), overlayLocal} {
+		m, err := readOverlay(dir, name, opt.env)
+		if errors.Is(err, fs.ErrNotExist) && name != overlayBase {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		merged = MergeMap(merged, m)
+	}
+
+	if err := opt.decryptValues(merged); err != nil {
+		return err
+	}
+	content, err := jsonx.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return LoadFromJsonBytes(content, v)
+}
+
+// readOverlay 读取 dir 中名为 name 的配置文件并转换为 map
+func readOverlay(dir, name string, useEnv bool) (map[string]any, error) {
+	for _, ext := range overlayExts {
+		file := filepath.Join(dir, name+ext)
+		content, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if useEnv {
+			content = []byte(os.ExpandEnv(string(content)))
+		}
+
+		m, err := contentToMap(ext, content)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", file, err)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("config file %s: %w", filepath.Join(dir, name+".yaml"), fs.ErrNotExist)
+}
+
+// MergeMap 将 src 深度合并到 dst 并返回 dst，两边都是 map 的字段递归合并，其余字段（包括数组）以 src 为准，
+// dst 为 nil 时创建新的 map
+func MergeMap(dst, src map[string]any) map[string]any {
+	if dst == nil {
+		dst = make(map[string]any, len(src))
+	}
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				dst[k] = MergeMap(dm, sm)
+				continue
+			}
+		}
+		dst[k] = sv
+	}
+	return dst
+}
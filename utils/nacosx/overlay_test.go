@@ -0,0 +1,97 @@
+package nacosx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betacats/go-core/utils/envx"
+)
+
+type overlayConfig struct {
+	Name  string
+	Port  int
+	Hosts []string `json:",optional"`
+	Mysql struct {
+		Host     string
+		Password string `json:",optional"`
+		MaxConns int    `json:",default=10"`
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestLoadEnvOverlay(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": `
+name: demo
+port: 8080
+hosts: [a, b]
+mysql:
+  host: localhost
+  password: ${MYSQL_PASSWORD}
+`,
+		"prod.yml": `
+hosts: [c]
+mysql:
+  host: mysql.prod
+  maxConns: 100
+`,
+		"test.json":  `{"port": 9090}`,
+		"local.toml": `port = 7070`,
+	})
+	t.Setenv("MYSQL_PASSWORD", "s3cret")
+
+	var c overlayConfig
+	assert.Nil(t, LoadEnvOverlay(dir, &c, WithProfile(envx.EnvProd), UseEnv()))
+	assert.Equal(t, "demo", c.Name)
+	assert.Equal(t, 7070, c.Port)
+	// 数组整体覆盖，map 递归合并
+	assert.Equal(t, []string{"c"}, c.Hosts)
+	assert.Equal(t, "mysql.prod", c.Mysql.Host)
+	assert.Equal(t, "s3cret", c.Mysql.Password)
+	assert.Equal(t, 100, c.Mysql.MaxConns)
+
+	assert.Nil(t, os.Remove(filepath.Join(dir, "local.toml")))
+	c = overlayConfig{}
+	assert.Nil(t, LoadEnvOverlay(dir, &c, WithProfile(envx.EnvTest)))
+	assert.Equal(t, 9090, c.Port)
+	assert.Equal(t, "localhost", c.Mysql.Host)
+	assert.Equal(t, "${MYSQL_PASSWORD}", c.Mysql.Password)
+	assert.Equal(t, 10, c.Mysql.MaxConns)
+
+	// 没有对应环境的文件时只使用 base
+	c = overlayConfig{}
+	assert.Nil(t, LoadEnvOverlay(dir, &c, WithProfile(envx.EnvUat)))
+	assert.Equal(t, 8080, c.Port)
+}
+
+func TestLoadEnvOverlayErrors(t *testing.T) {
+	var c overlayConfig
+	assert.ErrorContains(t, LoadEnvOverlay(t.TempDir(), &c, WithProfile(envx.EnvDev)), "base.yaml")
+
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": "name: demo\nport: 8080\nmysql:\n  host: localhost\n",
+		"dev.yaml":  "port: [\n",
+	})
+	assert.ErrorContains(t, LoadEnvOverlay(dir, &c, WithProfile(envx.EnvDev)), "dev.yaml")
+}
+
+func TestMergeMap(t *testing.T) {
+	m := MergeMap(nil, map[string]any{"name": "demo", "mysql": map[string]any{"host": "db1", "port": 3306}, "hosts": []any{"a", "b"}})
+	m = MergeMap(m, map[string]any{"mysql": map[string]any{"host": "db2"}, "hosts": []any{"c"}})
+	assert.Equal(t, map[string]any{
+		"name":  "demo",
+		"mysql": map[string]any{"host": "db2", "port": 3306},
+		"hosts": []any{"c"},
+	}, m)
+	assert.Empty(t, MergeMap(nil, nil))
+}
@@ -0,0 +1,343 @@
+package nacosx
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+const defaultGroup = "DEFAULT_GROUP"
+
+// Strategy 实例选择策略
+type Strategy int
+
+const (
+	// StrategyRoundRobin 平滑加权轮询，默认值
+	StrategyRoundRobin Strategy = iota
+	// StrategyRandom 加权随机
+	StrategyRandom
+	// StrategyConsistentHash 按 WithHashKey 一致性哈希，没有 key 时加权随机
+	StrategyConsistentHash
+)
+
+// ErrNoInstance 没有满足条件的健康实例
+var ErrNoInstance = errors.New("nacosx: no available instance")
+
+type (
+	// Target 要解析的服务
+	Target struct {
+		Service  string            // 服务名称
+		Group    string            // 服务分组，默认 DEFAULT_GROUP
+		Clusters []string          // 服务集群，为空时不限制
+		Metadata map[string]string // 实例元数据必须包含这些键值
+	}
+
+	// ResolverOption 配置 Resolver
+	ResolverOption func(r *Resolver)
+
+	// PickOption 配置单次选择
+	PickOption func(o *pickOptions)
+
+	pickOptions struct {
+		hashKey string
+		exclude map[string]bool
+	}
+
+	// Resolver 订阅服务实例变化并在本地缓存健康实例，按策略选择实例。
+	// 每个服务只在第一次解析时查询并订阅一次
+	Resolver struct {
+		n        *Nacosx
+		strategy Strategy
+
+		mu       sync.Mutex
+		services map[string]*service
+	}
+
+	service struct {
+		param *vo.SubscribeParam
+		ready chan struct{} // 第一次查询和订阅完成后关闭
+		err   error         // 第一次查询或订阅的错误，ready 关闭后可读
+
+		notify    sync.Mutex // 串行化实例更新和订阅回调，保证回调按实例变化的顺序到达
+		mu        sync.RWMutex
+		instances []model.Instance
+		balancers map[string]balancer
+		subs      map[int]func([]model.Instance)
+		nextID    int
+	}
+)
+
+// WithStrategy 设置实例选择策略
+func WithStrategy(s Strategy) ResolverOption {
+	return func(r *Resolver) {
+		r.strategy = s
+	}
+}
+
+// WithHashKey 设置一致性哈希的 key，如用户 ID
+func WithHashKey(key string) PickOption {
+	return func(o *pickOptions) {
+		o.hashKey = key
+	}
+}
+
+// Exclude 排除指定的实例，addr 格式为 host:port，如重试时排除已失败的实例
+func Exclude(addrs ...string) PickOption {
+	return func(o *pickOptions) {
+		if o.exclude == nil {
+			o.exclude = make(map[string]bool, len(addrs))
+		}
+		for _, addr := range addrs {
+			o.exclude[addr] = true
+		}
+	}
+}
+
+// NewResolver 创建服务解析器，需要命名客户端
+func NewResolver(n *Nacosx, opts ...ResolverOption) (*Resolver, error) {
+	if n.namingClient == nil {
+		return nil, ErrNamingClientDisabled
+	}
+	r := &Resolver{n: n, services: make(map[string]*service)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Instances 返回服务当前满足条件的健康实例
+func (r *Resolver) Instances(t Target) ([]model.Instance, error) {
+	svc, err := r.service(t)
+	if err != nil {
+		return nil, err
+	}
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	return filterMetadata(svc.instances, t.Metadata), nil
+}
+
+// Pick 按策略选择一个满足条件的健康实例
+func (r *Resolver) Pick(t Target, opts ...PickOption) (model.Instance, error) {
+	svc, err := r.service(t)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	var o pickOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	inst, ok := svc.balancer(r.strategy, t.Metadata).pick(o)
+	if !ok {
+		return model.Instance{}, fmt.Errorf("%w: %s@%s", ErrNoInstance, t.Service, groupOf(t))
+	}
+	return inst, nil
+}
+
+// Subscribe 立即以当前实例调用 fn，之后每次实例变化时按变化的顺序调用，返回取消订阅的函数。
+// 回调串行执行，fn 中不应再订阅同一个服务
+func (r *Resolver) Subscribe(t Target, fn func(instances []model.Instance)) (func(), error) {
+	svc, err := r.service(t)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.notify.Lock()
+	defer svc.notify.Unlock()
+	svc.mu.Lock()
+	id := svc.nextID
+	svc.nextID++
+	svc.subs[id] = func(instances []model.Instance) {
+		fn(filterMetadata(instances, t.Metadata))
+	}
+	instances := svc.instances
+	svc.mu.Unlock()
+
+	fn(filterMetadata(instances, t.Metadata))
+	return func() {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		delete(svc.subs, id)
+	}, nil
+}
+
+// Close 取消所有订阅
+func (r *Resolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var errs []error
+	for key, svc := range r.services {
+		delete(r.services, key)
+		// 仍在初始化的服务在订阅完成后发现已被移除，自行取消订阅
+		select {
+		case <-svc.ready:
+		default:
+			continue
+		}
+		if svc.err != nil {
+			continue
+		}
+		if err := r.n.namingClient.Unsubscribe(svc.param); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// service 返回服务的本地缓存，第一次解析时查询实例并订阅变化
+func (r *Resolver) service(t Target) (*service, error) {
+	if t.Service == "" {
+		return nil, errors.New("nacosx: empty service name")
+	}
+	clusters := slices.Clone(t.Clusters)
+	sort.Strings(clusters)
+	group := groupOf(t)
+	key := strings.Join([]string{group, t.Service, strings.Join(clusters, ",")}, "@@")
+
+	// 查询和订阅在锁外进行，慢的服务不会阻塞其他已缓存服务的解析，同一服务的并发解析等待同一次初始化
+	r.mu.Lock()
+	svc, ok := r.services[key]
+	if !ok {
+		svc = &service{ready: make(chan struct{}), subs: make(map[int]func([]model.Instance))}
+		r.services[key] = svc
+	}
+	r.mu.Unlock()
+	if ok {
+		<-svc.ready
+		return svc, svc.err
+	}
+
+	svc.err = r.init(svc, t.Service, group, clusters)
+	close(svc.ready)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.services[key] != svc {
+		// 初始化期间 Resolver 被关闭
+		if svc.err == nil {
+			_ = r.n.namingClient.Unsubscribe(svc.param)
+		}
+		return svc, svc.err
+	}
+	if svc.err != nil {
+		// 失败的初始化不缓存，下次解析时重试
+		delete(r.services, key)
+	}
+	return svc, svc.err
+}
+
+// init 查询服务的健康实例并订阅变化
+func (r *Resolver) init(svc *service, name, group string, clusters []string) error {
+	instances, err := r.n.namingClient.SelectInstances(vo.SelectInstancesParam{
+		ServiceName: name,
+		GroupName:   group,
+		Clusters:    clusters,
+		HealthyOnly: true,
+	})
+	if err != nil {
+		return fmt.Errorf("select instances %s@%s: %w", name, group, err)
+	}
+
+	svc.update(instances)
+	svc.param = &vo.SubscribeParam{
+		ServiceName: name,
+		GroupName:   group,
+		Clusters:    clusters,
+		SubscribeCallback: func(instances []model.Instance, err error) {
+			if err != nil {
+				logger.Warn("subscribe instances failed, keep cached instances",
+					"service", name, "group", group, "error", err)
+				return
+			}
+			svc.update(instances)
+		},
+	}
+	if err = r.n.namingClient.Subscribe(svc.param); err != nil {
+		return fmt.Errorf("subscribe %s@%s: %w", name, group, err)
+	}
+	return nil
+}
+
+// update 替换缓存的实例，只保留健康、启用且权重大于 0 的实例
+func (s *service) update(instances []model.Instance) {
+	healthy := make([]model.Instance, 0, len(instances))
+	for _, inst := range instances {
+		if inst.Healthy && inst.Enable && inst.Weight > 0 {
+			healthy = append(healthy, inst)
+		}
+	}
+	sort.Slice(healthy, func(i, j int) bool { return InstanceAddr(healthy[i]) < InstanceAddr(healthy[j]) })
+
+	s.notify.Lock()
+	defer s.notify.Unlock()
+	s.mu.Lock()
+	s.instances = healthy
+	s.balancers = make(map[string]balancer)
+	subs := slices.Collect(maps.Values(s.subs))
+	s.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(healthy)
+	}
+}
+
+// balancer 返回元数据过滤后实例的 balancer，实例变化前复用以保持轮询状态
+func (s *service) balancer(strategy Strategy, metadata map[string]string) balancer {
+	key := metadataKey(metadata)
+	s.mu.RLock()
+	b, ok := s.balancers[key]
+	s.mu.RUnlock()
+	if ok {
+		return b
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok = s.balancers[key]; !ok {
+		b = newBalancer(strategy, filterMetadata(s.instances, metadata))
+		s.balancers[key] = b
+	}
+	return b
+}
+
+func filterMetadata(instances []model.Instance, metadata map[string]string) []model.Instance {
+	res := make([]model.Instance, 0, len(instances))
+	for _, inst := range instances {
+		if matchMetadata(inst, metadata) {
+			res = append(res, inst)
+		}
+	}
+	return res
+}
+
+func matchMetadata(inst model.Instance, metadata map[string]string) bool {
+	for k, v := range metadata {
+		if inst.Metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func metadataKey(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func groupOf(t Target) string {
+	if t.Group == "" {
+		return defaultGroup
+	}
+	return t.Group
+}
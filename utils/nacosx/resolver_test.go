@@ -0,0 +1,300 @@
+package nacosx
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNamingClient 内存中的注册中心，setInstances 时同步回调订阅者
+type fakeNamingClient struct {
+	naming_client.INamingClient
+
+	mu        sync.Mutex
+	instances map[string][]model.Instance
+	subs      map[string][]*vo.SubscribeParam
+	selects   int
+	block     map[string]chan struct{} // 查询该服务时阻塞直到 channel 关闭
+}
+
+func newFakeNamingClient() *fakeNamingClient {
+	return &fakeNamingClient{
+		instances: make(map[string][]model.Instance),
+		subs:      make(map[string][]*vo.SubscribeParam),
+	}
+}
+
+func (f *fakeNamingClient) SelectInstances(param vo.SelectInstancesParam) ([]model.Instance, error) {
+	f.mu.Lock()
+	block := f.block[param.ServiceName]
+	f.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.selects++
+	var res []model.Instance
+	for _, inst := range f.instances[param.GroupName+"/"+param.ServiceName] {
+		if inst.Healthy == param.HealthyOnly {
+			res = append(res, inst)
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.New("instance list is empty")
+	}
+	return res, nil
+}
+
+func (f *fakeNamingClient) Subscribe(param *vo.SubscribeParam) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := param.GroupName + "/" + param.ServiceName
+	f.subs[key] = append(f.subs[key], param)
+	return nil
+}
+
+func (f *fakeNamingClient) Unsubscribe(param *vo.SubscribeParam) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := param.GroupName + "/" + param.ServiceName
+	for i, p := range f.subs[key] {
+		if p == param {
+			f.subs[key] = append(f.subs[key][:i], f.subs[key][i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeNamingClient) setInstances(group, service string, instances ...model.Instance) {
+	f.mu.Lock()
+	key := group + "/" + service
+	f.instances[key] = instances
+	subs := append([]*vo.SubscribeParam(nil), f.subs[key]...)
+	f.mu.Unlock()
+	for _, p := range subs {
+		p.SubscribeCallback(instances, nil)
+	}
+}
+
+func testInstance(ip string, weight float64, metadata map[string]string) model.Instance {
+	return model.Instance{Ip: ip, Port: 8080, Weight: weight, Healthy: true, Enable: true, Metadata: metadata}
+}
+
+func newTestResolver(t *testing.T, opts ...ResolverOption) (*Resolver, *fakeNamingClient) {
+	client := newFakeNamingClient()
+	r, err := NewResolver(&Nacosx{config: &config{}, namingClient: client}, opts...)
+	assert.Nil(t, err)
+	return r, client
+}
+
+func TestResolverRoundRobin(t *testing.T) {
+	r, client := newTestResolver(t)
+	client.setInstances(defaultGroup, "order",
+		testInstance("10.0.0.1", 3, nil),
+		testInstance("10.0.0.2", 1, nil),
+		model.Instance{Ip: "10.0.0.3", Port: 8080, Weight: 1, Healthy: false, Enable: true},
+	)
+
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		inst, err := r.Pick(Target{Service: "order"})
+		assert.Nil(t, err)
+		counts[inst.Ip]++
+	}
+	assert.Equal(t, map[string]int{"10.0.0.1": 6, "10.0.0.2": 2}, counts)
+	assert.Equal(t, 1, client.selects)
+
+	// 订阅推送的变化更新本地缓存，不健康和禁用的实例被过滤
+	client.setInstances(defaultGroup, "order",
+		testInstance("10.0.0.2", 1, nil),
+		model.Instance{Ip: "10.0.0.4", Port: 8080, Weight: 1, Healthy: true, Enable: false},
+	)
+	inst, err := r.Pick(Target{Service: "order"})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.2", inst.Ip)
+
+	_, err = r.Pick(Target{Service: "order"}, Exclude("10.0.0.2:8080"))
+	assert.ErrorIs(t, err, ErrNoInstance)
+
+	_, err = r.Pick(Target{Service: "unknown"})
+	assert.NotNil(t, err)
+
+	assert.Nil(t, r.Close())
+	assert.Empty(t, client.subs[defaultGroup+"/order"])
+}
+
+func TestResolverRandom(t *testing.T) {
+	r, client := newTestResolver(t, WithStrategy(StrategyRandom))
+	client.setInstances("SHARED", "user",
+		testInstance("10.0.0.1", 9, map[string]string{"zone": "a"}),
+		testInstance("10.0.0.2", 1, map[string]string{"zone": "a"}),
+		testInstance("10.0.0.3", 1, map[string]string{"zone": "b"}),
+	)
+
+	target := Target{Service: "user", Group: "SHARED", Metadata: map[string]string{"zone": "a"}}
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		inst, err := r.Pick(target)
+		assert.Nil(t, err)
+		counts[inst.Ip]++
+	}
+	assert.Zero(t, counts["10.0.0.3"])
+	assert.Greater(t, counts["10.0.0.1"], counts["10.0.0.2"]*4)
+
+	inst, err := r.Pick(target, Exclude("10.0.0.1:8080"))
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.2", inst.Ip)
+
+	instances, err := r.Instances(Target{Service: "user", Group: "SHARED", Metadata: map[string]string{"zone": "b"}})
+	assert.Nil(t, err)
+	assert.Len(t, instances, 1)
+}
+
+func TestResolverConsistentHash(t *testing.T) {
+	r, client := newTestResolver(t, WithStrategy(StrategyConsistentHash))
+	var instances []model.Instance
+	for i := 1; i <= 4; i++ {
+		instances = append(instances, testInstance(fmt.Sprintf("10.0.0.%d", i), 1, nil))
+	}
+	client.setInstances(defaultGroup, "cart", instances...)
+
+	target := Target{Service: "cart"}
+	picked := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		inst, err := r.Pick(target, WithHashKey(key))
+		assert.Nil(t, err)
+		again, _ := r.Pick(target, WithHashKey(key))
+		assert.Equal(t, inst.Ip, again.Ip)
+		picked[key] = inst.Ip
+	}
+
+	// 移除一个实例只影响原本落在该实例上的 key
+	client.setInstances(defaultGroup, "cart", instances[:3]...)
+	for key, ip := range picked {
+		inst, err := r.Pick(target, WithHashKey(key))
+		assert.Nil(t, err)
+		if ip != "10.0.0.4" {
+			assert.Equal(t, ip, inst.Ip)
+		}
+	}
+
+	// 排除的实例顺时针跳过
+	inst, _ := r.Pick(target, WithHashKey("user-1"))
+	other, err := r.Pick(target, WithHashKey("user-1"), Exclude(InstanceAddr(inst)))
+	assert.Nil(t, err)
+	assert.NotEqual(t, inst.Ip, other.Ip)
+}
+
+func TestResolverSubscribe(t *testing.T) {
+	r, client := newTestResolver(t)
+	client.setInstances(defaultGroup, "order", testInstance("10.0.0.1", 1, nil))
+
+	var got [][]model.Instance
+	cancel, err := r.Subscribe(Target{Service: "order"}, func(instances []model.Instance) {
+		got = append(got, instances)
+	})
+	assert.Nil(t, err)
+	client.setInstances(defaultGroup, "order", testInstance("10.0.0.1", 1, nil), testInstance("10.0.0.2", 1, nil))
+	cancel()
+	client.setInstances(defaultGroup, "order")
+
+	assert.Len(t, got, 2)
+	assert.Len(t, got[0], 1)
+	assert.Len(t, got[1], 2)
+
+	_, err = NewResolver(newTestNacosx(t, newFakeConfigClient()))
+	assert.ErrorIs(t, err, ErrNamingClientDisabled)
+}
+
+func TestResolverSlowService(t *testing.T) {
+	r, client := newTestResolver(t)
+	client.setInstances(defaultGroup, "order", testInstance("10.0.0.1", 1, nil))
+	client.setInstances(defaultGroup, "slow", testInstance("10.0.0.2", 1, nil))
+	_, err := r.Pick(Target{Service: "order"})
+	assert.Nil(t, err)
+
+	release := make(chan struct{})
+	client.mu.Lock()
+	client.block = map[string]chan struct{}{"slow": release}
+	client.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			inst, err := r.Pick(Target{Service: "slow"})
+			assert.Nil(t, err)
+			assert.Equal(t, "10.0.0.2", inst.Ip)
+		})
+	}
+
+	// 慢服务的查询不阻塞已缓存服务
+	inst, err := r.Pick(Target{Service: "order"})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", inst.Ip)
+
+	close(release)
+	wg.Wait()
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	assert.Equal(t, 2, client.selects)
+	assert.Len(t, client.subs[defaultGroup+"/slow"], 1)
+}
+
+func TestResolverRetryFailedService(t *testing.T) {
+	r, client := newTestResolver(t)
+	_, err := r.Pick(Target{Service: "order"})
+	assert.NotNil(t, err)
+
+	client.setInstances(defaultGroup, "order", testInstance("10.0.0.1", 1, nil))
+	inst, err := r.Pick(Target{Service: "order"})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", inst.Ip)
+}
+
+func TestResolverSubscribeOrder(t *testing.T) {
+	r, client := newTestResolver(t)
+	client.setInstances(defaultGroup, "order", testInstance("10.0.0.1", 1, nil))
+
+	var (
+		mu  sync.Mutex
+		got []int
+	)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		<-start
+		for i := 2; i <= 20; i++ {
+			instances := make([]model.Instance, i)
+			for j := range instances {
+				instances[j] = testInstance(fmt.Sprintf("10.0.0.%d", j+1), 1, nil)
+			}
+			client.setInstances(defaultGroup, "order", instances...)
+		}
+	})
+	// 订阅与推送并发
+	close(start)
+	_, err := r.Subscribe(Target{Service: "order"}, func(instances []model.Instance) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, len(instances))
+	})
+	assert.Nil(t, err)
+	wg.Wait()
+
+	// 初始快照和推送经同一路径串行送达，实例数只增不减
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, slices.IsSorted(got))
+	assert.Equal(t, 20, got[len(got)-1])
+}
@@ -0,0 +1,249 @@
+package nacosx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+const (
+	// SecretKeyEnv 保存 base64 编码的 AES 密钥的环境变量
+	SecretKeyEnv = "NACOSX_SECRET_KEY"
+	// SecretKeyFileEnv 保存密钥文件路径的环境变量，文件内容为 base64 编码的 AES 密钥
+	SecretKeyFileEnv = "NACOSX_SECRET_KEY_FILE"
+
+	encPrefix = "ENC("
+	encSuffix = ")"
+	redacted  = "******"
+)
+
+// ErrSecretKeyNotFound 配置中有加密值但没有设置密钥
+var ErrSecretKeyNotFound = errors.New("secret key not found, set " + SecretKeyEnv + " or " + SecretKeyFileEnv)
+
+var (
+	secretKeyMu sync.RWMutex
+	secretKey   []byte
+)
+
+// Secret 敏感配置，在日志、JSON 和 fmt 输出中脱敏，使用 Value 获取原文
+type Secret string
+
+// Value 返回原文
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String 返回脱敏后的值
+func (s Secret) String() string {
+	return redacted
+}
+
+// Format 实现 fmt.Formatter，所有格式均输出脱敏后的值
+func (s Secret) Format(f fmt.State, verb rune) {
+	_, _ = io.WriteString(f, redacted)
+}
+
+// MarshalJSON 输出脱敏后的值
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+// LogValue 实现 slog.LogValuer
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// SetSecretKey 设置解密使用的密钥，优先于环境变量，key 的长度必须是 16、24 或 32 字节
+func SetSecretKey(key []byte) error {
+	if _, err := aes.NewCipher(key); err != nil {
+		return err
+	}
+	secretKeyMu.Lock()
+	defer secretKeyMu.Unlock()
+	secretKey = key
+	return nil
+}
+
+// SecretKey 返回 WithDecrypt 解密使用的密钥，依次取 SetSecretKey、SecretKeyEnv 和 SecretKeyFileEnv
+func SecretKey() ([]byte, error) {
+	secretKeyMu.RLock()
+	key := secretKey
+	secretKeyMu.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+
+	if encoded := os.Getenv(SecretKeyEnv); encoded != "" {
+		return decodeSecretKey(encoded)
+	}
+	if file := os.Getenv(SecretKeyFileEnv); file != "" {
+		return ReadSecretKeyFile(file)
+	}
+	return nil, ErrSecretKeyNotFound
+}
+
+// ReadSecretKeyFile 读取 base64 编码的 AES 密钥文件
+func ReadSecretKeyFile(file string) ([]byte, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read secret key file: %w", err)
+	}
+	return decodeSecretKey(string(content))
+}
+
+func decodeSecretKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode secret key: %w", err)
+	}
+	if _, err = aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GenerateSecretKey 生成 32 字节的随机密钥
+func GenerateSecretKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt 使用 AES-GCM 加密 plaintext，返回可以直接写入配置的 ENC(base64) 格式
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed) + encSuffix, nil
+}
+
+// Decrypt 解密 ENC(base64) 格式的值
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encPrefix) : len(value)-len(encSuffix)])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted 判断值是否为 ENC(base64) 格式
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptValues 开启 WithDecrypt 时解密 m 中所有 ENC(base64) 格式的字符串，
+// 未指定密钥时只有存在加密值才读取 SecretKey
+func (o options) decryptValues(m map[string]any) error {
+	if !o.decrypt {
+		return nil
+	}
+	key := o.key
+	var walk func(path string, v any) (any, error)
+	walk = func(path string, v any) (any, error) {
+		switch vv := v.(type) {
+		case map[string]any:
+			for k, item := range vv {
+				res, err := walk(joinPath(path, k), item)
+				if err != nil {
+					return nil, err
+				}
+				vv[k] = res
+			}
+		case []any:
+			for i, item := range vv {
+				res, err := walk(fmt.Sprintf("%s[%d]", path, i), item)
+				if err != nil {
+					return nil, err
+				}
+				vv[i] = res
+			}
+		case string:
+			if !IsEncrypted(vv) {
+				return v, nil
+			}
+			if key == nil {
+				var err error
+				if key, err = SecretKey(); err != nil {
+					return nil, fmt.Errorf("decrypt %s: %w", path, err)
+				}
+			}
+			plaintext, err := Decrypt(key, vv)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt %s: %w", path, err)
+			}
+			return plaintext, nil
+		}
+		return v, nil
+	}
+
+	_, err := walk("", m)
+	return err
+}
+
+// parser 返回 format 格式的解析函数，开启 WithDecrypt 时先解密再解析
+func (o options) parser(format string) func([]byte, any) error {
+	if !o.decrypt {
+		return loaders[format]
+	}
+	return func(content []byte, v any) error {
+		m, err := contentToMap(format, content)
+		if err != nil {
+			return err
+		}
+		if err = o.decryptValues(m); err != nil {
+			return err
+		}
+		if content, err = jsonx.Marshal(m); err != nil {
+			return err
+		}
+		return LoadFromJsonBytes(content, v)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,143 @@
+package nacosx
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setTestSecretKey(t *testing.T) []byte {
+	key, err := GenerateSecretKey()
+	assert.Nil(t, err)
+	t.Setenv(SecretKeyEnv, base64.StdEncoding.EncodeToString(key))
+	t.Setenv(SecretKeyFileEnv, "")
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateSecretKey()
+	assert.Nil(t, err)
+
+	enc, err := Encrypt(key, "p@ss")
+	assert.Nil(t, err)
+	assert.True(t, IsEncrypted(enc))
+
+	plaintext, err := Decrypt(key, enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", plaintext)
+
+	other, _ := GenerateSecretKey()
+	_, err = Decrypt(other, enc)
+	assert.NotNil(t, err)
+	_, err = Decrypt(key, "p@ss")
+	assert.NotNil(t, err)
+}
+
+func TestSecretKey(t *testing.T) {
+	key, _ := GenerateSecretKey()
+	file := filepath.Join(t.TempDir(), "secret.key")
+	assert.Nil(t, os.WriteFile(file, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+
+	t.Setenv(SecretKeyEnv, "")
+	t.Setenv(SecretKeyFileEnv, "")
+	_, err := SecretKey()
+	assert.ErrorIs(t, err, ErrSecretKeyNotFound)
+
+	t.Setenv(SecretKeyFileEnv, file)
+	res, err := SecretKey()
+	assert.Nil(t, err)
+	assert.Equal(t, key, res)
+
+	t.Setenv(SecretKeyEnv, base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = SecretKey()
+	assert.NotNil(t, err)
+
+	res, err = ReadSecretKeyFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, key, res)
+	_, err = ReadSecretKeyFile(filepath.Join(t.TempDir(), "missing.key"))
+	assert.NotNil(t, err)
+}
+
+func TestLoadEncryptedValues(t *testing.T) {
+	key := setTestSecretKey(t)
+	enc, err := Encrypt(key, "p@ss")
+	assert.Nil(t, err)
+
+	type conf struct {
+		Mysql struct {
+			User     string
+			Password Secret
+		}
+		Tokens []string `json:",optional"`
+	}
+	content := fmt.Sprintf("mysql:\n  user: root\n  password: %s\ntokens:\n  - %s\n", enc, enc)
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(content), 0o600))
+
+	var c conf
+	assert.Nil(t, Load(file, &c, WithDecrypt()))
+	assert.Equal(t, "root", c.Mysql.User)
+	assert.Equal(t, "p@ss", c.Mysql.Password.Value())
+	assert.Equal(t, []string{"p@ss"}, c.Tokens)
+
+	client := newFakeConfigClient()
+	client.publish("DEFAULT_GROUP", "app.yaml", content)
+	n := newTestNacosx(t, client)
+	m, err := n.LoadMap(WithDecrypt())
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", m["mysql"].(map[string]any)["password"])
+	c = conf{}
+	assert.Nil(t, n.Load(&c, WithDecrypt()))
+	assert.Equal(t, "p@ss", c.Mysql.Password.Value())
+	w, err := NewWatch[conf](n, WithDecrypt())
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", w.Load().Mysql.Password.Value())
+
+	// 默认不解密，ENC(...) 形式的字面值原样加载
+	c = conf{}
+	assert.Nil(t, LoadFromYamlBytes([]byte(content), &c))
+	assert.Equal(t, enc, c.Mysql.Password.Value())
+	m, err = n.LoadMap()
+	assert.Nil(t, err)
+	assert.Equal(t, enc, m["mysql"].(map[string]any)["password"])
+
+	t.Setenv(SecretKeyEnv, "")
+	err = Load(file, &c, WithDecrypt())
+	assert.ErrorIs(t, err, ErrSecretKeyNotFound)
+	assert.ErrorContains(t, err, "decrypt ")
+
+	// 显式指定的密钥优先于环境变量
+	c = conf{}
+	assert.Nil(t, Load(file, &c, WithSecretKey(key)))
+	assert.Equal(t, "p@ss", c.Mysql.Password.Value())
+
+	// 没有加密值时不需要密钥
+	w2, err := NewWatchFromContent[conf]([]byte("mysql:\n  user: root\n  password: plain\n"), WithDecrypt())
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", w2.Load().Mysql.Password.Value())
+}
+
+func TestSecretRedact(t *testing.T) {
+	s := Secret("p@ss")
+	assert.Equal(t, redacted, s.String())
+	assert.Equal(t, redacted, fmt.Sprintf("%v %s %q %+v %#v", s, s, s, s, s)[:len(redacted)])
+	assert.NotContains(t, fmt.Sprintf("%v %s %q %+v %#v", s, s, s, s, s), "p@ss")
+	assert.NotContains(t, fmt.Sprintf("%+v", struct{ Password Secret }{s}), "p@ss")
+
+	body, err := json.Marshal(struct{ Password Secret }{s})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Password":"******"}`, string(body))
+
+	var buf strings.Builder
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("connect", "password", s)
+	assert.NotContains(t, buf.String(), "p@ss")
+	assert.Contains(t, buf.String(), "password="+redacted)
+}
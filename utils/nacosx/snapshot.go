@@ -0,0 +1,204 @@
+package nacosx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+const instrumentationName = "github.com/betacats/go-core/utils/nacosx"
+
+// errSnapshotCorrupted 快照校验和与内容不一致
+var errSnapshotCorrupted = errors.New("snapshot checksum mismatch")
+
+// reconcileInterval 使用快照期间重试获取服务端配置的间隔
+var reconcileInterval = 10 * time.Second
+
+var (
+	snapshotFallbacks metric.Int64Counter
+	snapshotActive    metric.Int64UpDownCounter
+)
+
+func init() {
+	meter := otel.Meter(instrumentationName)
+	snapshotFallbacks, _ = meter.Int64Counter("nacos.config.snapshot.fallbacks",
+		metric.WithDescription("Number of times a local snapshot was used because the nacos server was unavailable."))
+	snapshotActive, _ = meter.Int64UpDownCounter("nacos.config.snapshot.active",
+		metric.WithDescription("Number of config sources currently served from a local snapshot."))
+}
+
+// snapshot 本地保存的配置快照
+type snapshot struct {
+	Namespace string    `json:"namespace"`
+	Group     string    `json:"group"`
+	DataID    string    `json:"dataId"`
+	Content   string    `json:"content"`
+	Sha256    string    `json:"sha256"`
+	SavedAt   time.Time `json:"savedAt"`
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshotFile 返回配置源对应的快照文件路径
+func (s *Nacosx) snapshotFile(src ConfigSource) string {
+	name := strings.Join([]string{s.config.NamespaceID, src.Group, src.DataID}, "@@")
+	name = strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(name)
+	return filepath.Join(s.config.SnapshotDir, name+".json")
+}
+
+// saveSnapshot 原子写入配置源的快照，未配置快照目录时不保存
+func (s *Nacosx) saveSnapshot(src ConfigSource, content string) error {
+	if s.config.SnapshotDir == "" {
+		return nil
+	}
+	// 快照中可能包含明文密钥，目录和文件只允许当前用户访问
+	if err := os.MkdirAll(s.config.SnapshotDir, 0o700); err != nil {
+		return err
+	}
+
+	body, err := jsonx.Marshal(snapshot{
+		Namespace: s.config.NamespaceID,
+		Group:     src.Group,
+		DataID:    src.DataID,
+		Content:   content,
+		Sha256:    checksum(content),
+		SavedAt:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	file := s.snapshotFile(src)
+	tmp, err := os.CreateTemp(s.config.SnapshotDir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err = tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// readSnapshot 读取并校验配置源的快照
+func (s *Nacosx) readSnapshot(src ConfigSource) (snapshot, error) {
+	var snap snapshot
+	if s.config.SnapshotDir == "" {
+		return snap, errors.New("snapshot disabled")
+	}
+	body, err := os.ReadFile(s.snapshotFile(src))
+	if err != nil {
+		return snap, err
+	}
+	if err = jsonx.Unmarshal(body, &snap); err != nil {
+		return snap, err
+	}
+	if checksum(snap.Content) != snap.Sha256 {
+		return snap, errSnapshotCorrupted
+	}
+	return snap, nil
+}
+
+// saveSnapshots 保存所有从服务端获取的配置源的快照，应在配置解析成功后调用
+func (s *Nacosx) saveSnapshots(set *configSet) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	for i, src := range set.sources {
+		if set.stale[i] {
+			continue
+		}
+		if err := s.saveSnapshot(src, set.contents[i]); err != nil {
+			logger.Warn("failed to save config snapshot", "dataId", src.DataID, "group", src.Group, "error", err)
+		}
+	}
+}
+
+// fallback 服务端不可用时使用本地快照
+func (s *Nacosx) fallback(src ConfigSource, cause error) (string, error) {
+	snap, err := s.readSnapshot(src)
+	if err != nil {
+		return "", fmt.Errorf("get config %s@%s: %w", src.DataID, src.Group, errors.Join(cause, fmt.Errorf("snapshot: %w", err)))
+	}
+
+	attrs := metric.WithAttributes(attribute.String("dataId", src.DataID), attribute.String("group", src.Group))
+	snapshotFallbacks.Add(context.Background(), 1, attrs)
+	snapshotActive.Add(context.Background(), 1, attrs)
+	logger.Warn("nacos unavailable, using local snapshot",
+		"dataId", src.DataID, "group", src.Group, "savedAt", snap.SavedAt, "error", cause)
+	return snap.Content, nil
+}
+
+// reconcile 在后台重试获取使用快照的配置源，获取成功后以配置源和合并后的 JSON 回调，
+// 回调成功应用后应调用 markFresh，所有配置源恢复或 Nacosx 关闭后退出
+func (s *Nacosx) reconcile(set *configSet, onChange func(change)) {
+	if len(set.staleSources()) == 0 {
+		return
+	}
+	ctx, interval := s.context(), reconcileInterval
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			stale := set.staleSources()
+			if len(stale) == 0 {
+				return
+			}
+			for _, i := range stale {
+				src := set.sources[i]
+				content, err := s.configClient.GetConfig(vo.ConfigParam{DataId: src.DataID, Group: src.Group})
+				if err != nil {
+					continue
+				}
+				logger.Info("nacos recovered, reconciling config", "dataId", src.DataID, "group", src.Group)
+				onChange(set.update(i, content))
+			}
+		}
+	}()
+}
+
+// refreshSnapshots 服务端恢复后校验合并后的配置并刷新快照，不修改已加载的配置，validate 为空时只校验合并
+func (s *Nacosx) refreshSnapshots(set *configSet, validate func(merged []byte) error) {
+	s.reconcile(set, func(c change) {
+		err := c.err
+		if err == nil && validate != nil {
+			err = validate(c.merged)
+		}
+		if err != nil {
+			logger.Warn("recovered config rejected, keep using snapshot", "error", err)
+			return
+		}
+		set.markFresh(c.source)
+		s.saveSnapshots(set)
+		logger.Info("config snapshot refreshed, reload to apply the latest config")
+	})
+}
@@ -0,0 +1,181 @@
+package nacosx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotFallback(t *testing.T) {
+	errDown := errors.New("server down")
+	client := newFakeConfigClient()
+	n := newTestNacosx(t, client)
+	n.config.SnapshotDir = t.TempDir()
+
+	// 没有快照时返回原始错误
+	client.setError(errDown)
+	var c watchConfig
+	assert.ErrorIs(t, n.Load(&c), errDown)
+
+	client.setError(nil)
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 8080\nmysql:\n  host: db\n")
+	assert.Nil(t, n.Load(&c))
+
+	client.setError(errDown)
+	c = watchConfig{}
+	assert.Nil(t, n.Load(&c))
+	assert.Equal(t, "demo", c.Name)
+	assert.Equal(t, 8080, c.Port)
+
+	t.Run("corrupted", func(t *testing.T) {
+		file := n.snapshotFile(ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"})
+		body, err := os.ReadFile(file)
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(file, []byte(string(body[:len(body)-2])+`x"}`), 0o644))
+
+		_, err = n.readSnapshot(ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"})
+		assert.NotNil(t, err)
+		assert.ErrorIs(t, n.Load(&c), errDown)
+	})
+}
+
+func TestSnapshotInvalidConfigNotSaved(t *testing.T) {
+	client := newFakeConfigClient()
+	n := newTestNacosx(t, client)
+	n.config.SnapshotDir = t.TempDir()
+
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\n")
+	var c watchConfig
+	assert.NotNil(t, n.Load(&c))
+	_, err := n.readSnapshot(ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"})
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWatchReconcile(t *testing.T) {
+	interval := reconcileInterval
+	reconcileInterval = 10 * time.Millisecond
+	t.Cleanup(func() { reconcileInterval = interval })
+
+	client := newFakeConfigClient()
+	n := newTestNacosx(t, client)
+	n.config.SnapshotDir = t.TempDir()
+	src := ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"}
+	assert.Nil(t, n.saveSnapshot(src, "name: demo\nport: 8080\nmysql:\n  host: db\n"))
+
+	client.setError(errors.New("server down"))
+	w, err := NewWatch[watchConfig](n)
+	assert.Nil(t, err)
+	assert.Equal(t, 8080, w.Load().Port)
+
+	// 服务端恢复后自动更新，并刷新快照
+	client.mu.Lock()
+	client.contents["DEFAULT_GROUP/app.yaml"] = "name: demo\nport: 9090\nmysql:\n  host: db\n"
+	client.err = nil
+	client.mu.Unlock()
+	assert.Eventually(t, func() bool { return w.Load().Port == 9090 }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		snap, err := n.readSnapshot(src)
+		return err == nil && snap.Content == "name: demo\nport: 9090\nmysql:\n  host: db\n"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReconcileRetriesRejectedConfig(t *testing.T) {
+	interval := reconcileInterval
+	reconcileInterval = 10 * time.Millisecond
+	t.Cleanup(func() { reconcileInterval = interval })
+
+	client := newFakeConfigClient()
+	n := newTestNacosx(t, client)
+	n.config.SnapshotDir = t.TempDir()
+	src := ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"}
+	assert.Nil(t, n.saveSnapshot(src, "name: demo\nport: 8080\nmysql:\n  host: db\n"))
+
+	client.setError(errors.New("server down"))
+	w, err := NewWatch[watchConfig](n)
+	assert.Nil(t, err)
+
+	// 服务端恢复后返回的配置校验失败时继续使用快照并重试
+	client.mu.Lock()
+	client.contents["DEFAULT_GROUP/app.yaml"] = "name: demo\nport: 0\nmysql:\n  host: db\n"
+	client.err = nil
+	client.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 8080, w.Load().Port)
+
+	client.mu.Lock()
+	client.contents["DEFAULT_GROUP/app.yaml"] = "name: demo\nport: 9090\nmysql:\n  host: db\n"
+	client.mu.Unlock()
+	assert.Eventually(t, func() bool { return w.Load().Port == 9090 }, time.Second, 10*time.Millisecond)
+}
+
+func TestLoadReconcile(t *testing.T) {
+	interval := reconcileInterval
+	reconcileInterval = 10 * time.Millisecond
+	t.Cleanup(func() { reconcileInterval = interval })
+
+	client := newFakeConfigClient()
+	n := newTestNacosx(t, client)
+	n.config.SnapshotDir = t.TempDir()
+	src := ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"}
+	assert.Nil(t, n.saveSnapshot(src, "name: demo\nport: 8080\nmysql:\n  host: db\n"))
+
+	client.setError(errors.New("server down"))
+	var c watchConfig
+	assert.Nil(t, n.Load(&c))
+	assert.Equal(t, 8080, c.Port)
+
+	// 服务端恢复后刷新快照，已加载的配置不变
+	client.mu.Lock()
+	client.contents["DEFAULT_GROUP/app.yaml"] = "name: demo\nport: 9090\nmysql:\n  host: db\n"
+	client.err = nil
+	client.mu.Unlock()
+	assert.Eventually(t, func() bool {
+		snap, err := n.readSnapshot(src)
+		return err == nil && snap.Content == "name: demo\nport: 9090\nmysql:\n  host: db\n"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 8080, c.Port)
+}
+
+func TestReconcileStopsOnClose(t *testing.T) {
+	interval := reconcileInterval
+	reconcileInterval = 10 * time.Millisecond
+	t.Cleanup(func() { reconcileInterval = interval })
+
+	client := newFakeConfigClient()
+	n := newTestNacosx(t, client)
+	n.config.SnapshotDir = t.TempDir()
+	src := ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"}
+	assert.Nil(t, n.saveSnapshot(src, "name: demo\nport: 8080\nmysql:\n  host: db\n"))
+
+	client.setError(errors.New("server down"))
+	w, err := NewWatch[watchConfig](n)
+	assert.Nil(t, err)
+	n.Close()
+
+	client.mu.Lock()
+	client.contents["DEFAULT_GROUP/app.yaml"] = "name: demo\nport: 9090\nmysql:\n  host: db\n"
+	client.err = nil
+	client.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 8080, w.Load().Port)
+}
+
+func TestSnapshotPermissions(t *testing.T) {
+	assert.Empty(t, NewBuilder().config.SnapshotDir)
+
+	n := newTestNacosx(t, newFakeConfigClient())
+	n.config.SnapshotDir = filepath.Join(t.TempDir(), "snapshot")
+	src := ConfigSource{DataID: "app.yaml", Group: "DEFAULT_GROUP"}
+	assert.Nil(t, n.saveSnapshot(src, "password: secret\n"))
+
+	dir, err := os.Stat(n.config.SnapshotDir)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o700), dir.Mode().Perm())
+	file, err := os.Stat(n.snapshotFile(src))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), file.Mode().Perm())
+}
@@ -0,0 +1,293 @@
+package nacosx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/betacats/go-core/utils/encoding"
+	"github.com/betacats/go-core/utils/jsonx"
+)
+
+// ConfigSource 一个 nacos 配置源
+type ConfigSource struct {
+	DataID string
+	Group  string // 为空时使用服务分组
+}
+
+type (
+	// configSet 保存各配置源最新的内容，任一配置源变化时整体重新合并
+	configSet struct {
+		mu       sync.Mutex
+		sources  []ConfigSource
+		formats  []string
+		contents []string
+		stale    map[int]bool // 使用本地快照的配置源
+		env      bool
+		version  uint64 // 每次变化递增，用于丢弃乱序到达的旧版本
+	}
+
+	// change 一次配置源变化及合并后的结果
+	change struct {
+		source  int
+		version uint64
+		merged  []byte
+		err     error
+	}
+)
+
+// Sources 返回按合并顺序排列的配置源，未调用 Builder.WithConfigSources 时只有 dataId 和服务分组对应的配置
+func (s *Nacosx) Sources() []ConfigSource {
+	if len(s.config.Sources) == 0 {
+		return []ConfigSource{{DataID: s.config.DataID, Group: s.config.ServiceGroup}}
+	}
+	sources := make([]ConfigSource, len(s.config.Sources))
+	for i, src := range s.config.Sources {
+		if src.Group == "" {
+			src.Group = s.config.ServiceGroup
+		}
+		sources[i] = src
+	}
+	return sources
+}
+
+// fetchSources 获取所有配置源的内容
+func (s *Nacosx) fetchSources(opt options) (*configSet, error) {
+	if s.configClient == nil {
+		return nil, ErrConfigClientDisabled
+	}
+	set := &configSet{sources: s.Sources(), stale: make(map[int]bool), env: opt.env}
+	for i, src := range set.sources {
+		format, err := formatFor(src.DataID, opt)
+		if err != nil {
+			return nil, err
+		}
+		content, err := s.configClient.GetConfig(vo.ConfigParam{DataId: src.DataID, Group: src.Group})
+		if err != nil {
+			if content, err = s.fallback(src, err); err != nil {
+				return nil, err
+			}
+			set.stale[i] = true
+		}
+		set.formats = append(set.formats, format)
+		set.contents = append(set.contents, content)
+	}
+	return set, nil
+}
+
+// listenSources 分别监听每个配置源，任一配置源变化时以变化的配置源和合并后的 JSON 回调
+func (s *Nacosx) listenSources(set *configSet, onChange func(change)) error {
+	for i, src := range set.sources {
+		i := i
+		err := s.listen(src, func(namespace, group, dataID, data string) {
+			onChange(set.update(i, data))
+		})
+		if err != nil {
+			return fmt.Errorf("listen config %s@%s: %w", src.DataID, src.Group, err)
+		}
+	}
+	return nil
+}
+
+// listen 订阅配置源的变化，每个配置源只向 nacos 注册一个监听器，变化时依次回调所有订阅者
+func (s *Nacosx) listen(src ConfigSource, fn func(namespace, group, dataID, data string)) error {
+	s.mu.Lock()
+	if s.listeners == nil {
+		s.listeners = make(map[ConfigSource][]func(namespace, group, dataID, data string))
+	}
+	fns, registered := s.listeners[src]
+	s.listeners[src] = append(fns, fn)
+	s.mu.Unlock()
+	if registered {
+		return nil
+	}
+
+	err := s.configClient.ListenConfig(vo.ConfigParam{
+		DataId: src.DataID,
+		Group:  src.Group,
+		OnChange: func(namespace, group, dataID, data string) {
+			s.mu.Lock()
+			fns := slices.Clone(s.listeners[src])
+			s.mu.Unlock()
+			for _, fn := range fns {
+				fn(namespace, group, dataID, data)
+			}
+		},
+	})
+	if err != nil {
+		s.mu.Lock()
+		delete(s.listeners, src)
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// update 替换第 i 个配置源的内容并返回合并结果
+func (c *configSet) update(i int, content string) change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contents[i] = content
+	c.version++
+	merged, err := c.mergeLocked()
+	return change{source: i, version: c.version, merged: merged, err: err}
+}
+
+// markFresh 第 i 个配置源的服务端内容已成功应用，不再使用本地快照
+func (c *configSet) markFresh(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.stale[i] {
+		return
+	}
+	delete(c.stale, i)
+	src := c.sources[i]
+	snapshotActive.Add(context.Background(), -1, metric.WithAttributes(
+		attribute.String("dataId", src.DataID), attribute.String("group", src.Group)))
+}
+
+// staleSources 返回仍在使用本地快照的配置源
+func (c *configSet) staleSources() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]int, 0, len(c.stale))
+	for i := range c.stale {
+		res = append(res, i)
+	}
+	sort.Ints(res)
+	return res
+}
+
+// merged 按顺序深度合并所有配置源，返回 JSON
+func (c *configSet) merged() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mergeLocked()
+}
+
+func (c *configSet) mergedMap() (map[string]any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mergeMapLocked()
+}
+
+func (c *configSet) mergeLocked() ([]byte, error) {
+	merged, err := c.mergeMapLocked()
+	if err != nil {
+		return nil, err
+	}
+	return jsonx.Marshal(merged)
+}
+
+func (c *configSet) mergeMapLocked() (map[string]any, error) {
+	merged := make(map[string]any)
+	for i, content := range c.contents {
+		if c.env {
+			content = os.ExpandEnv(content)
+		}
+		m, err := contentToMap(c.formats[i], []byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("config %s@%s: %w", c.sources[i].DataID, c.sources[i].Group, err)
+		}
+		merged = MergeMap(merged, m)
+	}
+	return merged, nil
+}
+
+// formatFor 根据选项或 dataId 扩展名确定配置格式，扩展名不是已知格式时为 yaml，
+// 如 com.example.order。WithFormat 指定的格式未知时返回错误
+func formatFor(dataID string, opt options) (string, error) {
+	if opt.format != "" {
+		ext := strings.ToLower("." + strings.TrimPrefix(opt.format, "."))
+		if _, ok := loaders[ext]; !ok {
+			return "", fmt.Errorf("unrecognized config format: %s", ext)
+		}
+		return ext, nil
+	}
+	ext := strings.ToLower(path.Ext(dataID))
+	if _, ok := loaders[ext]; !ok {
+		return ".yaml", nil
+	}
+	return ext, nil
+}
+
+// contentToMap 将 format 格式的配置内容转换为 map
+func contentToMap(format string, content []byte) (map[string]any, error) {
+	var err error
+	switch format {
+	case ".yaml", ".yml":
+		content, err = encoding.YamlToJson(content)
+	case ".toml":
+		content, err = encoding.TomlToJson(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err = jsonx.Unmarshal(content, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadMap 加载所有配置源并按顺序合并为 map，开启 WithDecrypt 时解密 ENC(base64) 格式的值，供组合多种配置来源时使用
+func (s *Nacosx) LoadMap(opts ...Option) (map[string]any, error) {
+	var opt options
+	for _, o := range opts {
+		o(&opt)
+	}
+	set, err := s.fetchSources(opt)
+	if err != nil {
+		return nil, err
+	}
+	m, err := set.mergedMap()
+	if err != nil {
+		return nil, err
+	}
+	if err = opt.decryptValues(m); err != nil {
+		return nil, err
+	}
+	s.saveSnapshots(set)
+	s.refreshSnapshots(set, nil)
+	return m, nil
+}
+
+// LoadMap loads config file into a map, .json, .yaml, .yml and .toml are acceptable,
+// values in the form of ENC(base64) are decrypted when WithDecrypt or WithSecretKey is given.
+func LoadMap(file string, opts ...Option) (map[string]any, error) {
+	var opt options
+	for _, o := range opts {
+		o(&opt)
+	}
+	if _, ok := loaders[strings.ToLower(path.Ext(file))]; !ok && opt.format == "" {
+		return nil, fmt.Errorf("unrecognized file type: %s", file)
+	}
+	format, err := formatFor(file, opt)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if opt.env {
+		content = []byte(os.ExpandEnv(string(content)))
+	}
+	m, err := contentToMap(format, content)
+	if err != nil {
+		return nil, err
+	}
+	if err = opt.decryptValues(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,169 @@
+package nacosx
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Validator 配置实现该接口时，Watch 在替换快照前调用 Validate 校验
+	Validator interface {
+		Validate() error
+	}
+
+	// Watch 热更新的配置快照。每次配置变化时解析出新的快照，校验通过后原子替换，
+	// 解析或校验失败时保留上一个版本。读取方通过 Load 获取只读快照，不应修改其内容
+	Watch[T any] struct {
+		value atomic.Pointer[T]
+		parse func([]byte, any) error
+
+		mu      sync.Mutex // 保证快照按配置变化的顺序解析、替换和通知
+		subs    []func(old, new *T, changed []string)
+		version uint64 // NewWatch 最近一次应用的配置版本
+	}
+)
+
+// errStaleVersion 配置变化晚于更新的版本到达，已被丢弃
+var errStaleVersion = errors.New("nacosx: stale config version")
+
+// NewWatch 加载并合并所有配置源，分别监听每个配置源，任一变化时整体重新加载，初次加载失败时返回错误。
+// 格式的选择与 Nacosx.Load 相同。服务端不可用时使用本地快照启动，服务端恢复后自动更新。
+// 同一个 Nacosx 上的多个 Watch 和 ListenConfig 共享每个配置源的 nacos 监听器
+func NewWatch[T any](n *Nacosx, opts ...Option) (*Watch[T], error) {
+	var opt options
+	for _, o := range opts {
+		o(&opt)
+	}
+	set, err := n.fetchSources(opt)
+	if err != nil {
+		return nil, err
+	}
+	content, err := set.merged()
+	if err != nil {
+		return nil, err
+	}
+	w, err := newWatch[T](opt.parser(".json"), content)
+	if err != nil {
+		return nil, err
+	}
+
+	n.saveSnapshots(set)
+
+	apply := func(c change) {
+		err := c.err
+		if err == nil {
+			err = w.updateVersion(c.version, c.merged)
+		}
+		if errors.Is(err, errStaleVersion) {
+			return
+		}
+		if err != nil {
+			logger.Error("config update rejected, keep previous version", "error", err)
+			return
+		}
+		set.markFresh(c.source)
+		n.saveSnapshots(set)
+	}
+	if err = n.listenSources(set, apply); err != nil {
+		return nil, err
+	}
+	// 使用快照的配置源在服务端恢复后自动更新
+	n.reconcile(set, apply)
+	return w, nil
+}
+
+// NewWatchFromContent 从配置内容创建配置快照，不监听配置变化，格式由 WithFormat 指定，默认为 yaml
+func NewWatchFromContent[T any](content []byte, opts ...Option) (*Watch[T], error) {
+	var opt options
+	for _, o := range opts {
+		o(&opt)
+	}
+	format, err := formatFor("", opt)
+	if err != nil {
+		return nil, err
+	}
+	return newWatch[T](opt.parser(format), content)
+}
+
+func newWatch[T any](parse func([]byte, any) error, content []byte) (*Watch[T], error) {
+	w := &Watch[T]{parse: parse}
+	v, err := w.parseSnapshot(content)
+	if err != nil {
+		return nil, err
+	}
+	w.value.Store(v)
+	return w, nil
+}
+
+// Load 返回当前的配置快照
+func (w *Watch[T]) Load() *T {
+	return w.value.Load()
+}
+
+// Subscribe 订阅配置变化，仅在有字段变化时回调，changed 为变化的字段路径
+func (w *Watch[T]) Subscribe(fn func(old, new *T, changed []string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Update 解析并校验新的配置内容，格式与创建时一致（NewWatch 创建的为合并后的 JSON），
+// 成功后替换快照并通知订阅者，失败时保留当前快照并返回错误。并发调用时解析和替换整体串行，不会以旧内容覆盖新内容
+func (w *Watch[T]) Update(content []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.updateLocked(content)
+}
+
+// updateVersion 应用版本为 version 的合并结果，不晚于已应用版本的变化被丢弃并返回 errStaleVersion
+func (w *Watch[T]) updateVersion(version uint64, content []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if version <= w.version {
+		return errStaleVersion
+	}
+	w.version = version
+	return w.updateLocked(content)
+}
+
+func (w *Watch[T]) updateLocked(content []byte) error {
+	v, err := w.parseSnapshot(content)
+	if err != nil {
+		return err
+	}
+
+	old := w.value.Swap(v)
+	changed := ChangedPaths(old, v)
+	if len(changed) == 0 {
+		return nil
+	}
+	logger.Info("config updated", "changed", changed)
+	for _, fn := range w.subs {
+		notify(fn, old, v, changed)
+	}
+	return nil
+}
+
+func notify[T any](fn func(old, new *T, changed []string), old, new *T, changed []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("config subscriber panic", "panic", fmt.Sprint(r))
+		}
+	}()
+	fn(old, new, changed)
+}
+
+func (w *Watch[T]) parseSnapshot(content []byte) (*T, error) {
+	v := new(T)
+	if err := w.parse(content, v); err != nil {
+		return nil, err
+	}
+	if validator, ok := any(v).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
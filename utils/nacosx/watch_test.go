@@ -0,0 +1,259 @@
+package nacosx
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConfigClient 内存中的配置中心，Publish 时同步回调监听器
+type fakeConfigClient struct {
+	config_client.IConfigClient
+
+	mu        sync.Mutex
+	err       error // 不为空时 GetConfig 返回该错误，模拟服务端不可用
+	contents  map[string]string
+	listeners map[string]func(namespace, group, dataId, data string)
+}
+
+func newFakeConfigClient() *fakeConfigClient {
+	return &fakeConfigClient{
+		contents:  make(map[string]string),
+		listeners: make(map[string]func(namespace, group, dataId, data string)),
+	}
+}
+
+func (f *fakeConfigClient) GetConfig(param vo.ConfigParam) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return "", f.err
+	}
+	content, ok := f.contents[param.Group+"/"+param.DataId]
+	if !ok {
+		return "", errors.New("config not found")
+	}
+	return content, nil
+}
+
+func (f *fakeConfigClient) ListenConfig(param vo.ConfigParam) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := param.Group + "/" + param.DataId
+	// 与 nacos 一致，同一个 dataId 只保留第一个监听器
+	if _, ok := f.listeners[key]; !ok {
+		f.listeners[key] = param.OnChange
+	}
+	return nil
+}
+
+func (f *fakeConfigClient) publish(group, dataID, content string) {
+	f.mu.Lock()
+	f.contents[group+"/"+dataID] = content
+	listener := f.listeners[group+"/"+dataID]
+	f.mu.Unlock()
+	if listener != nil {
+		listener("", group, dataID, content)
+	}
+}
+
+func (f *fakeConfigClient) setError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *fakeConfigClient) CloseClient() {}
+
+// newTestNacosx 创建使用 client 的 Nacosx，测试结束时关闭以停止后台任务
+func newTestNacosx(t *testing.T, client *fakeConfigClient) *Nacosx {
+	n := &Nacosx{
+		config:       &config{DataID: "app.yaml", ServiceGroup: "DEFAULT_GROUP"},
+		configClient: client,
+	}
+	t.Cleanup(n.Close)
+	return n
+}
+
+type watchConfig struct {
+	Name    string
+	Port    int
+	Mysql   struct{ Host string }
+	Modules map[string]string `json:",optional"`
+}
+
+func (c *watchConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	client := newFakeConfigClient()
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 8080\nmysql:\n  host: db1\n")
+
+	w, err := NewWatch[watchConfig](newTestNacosx(t, client))
+	assert.Nil(t, err)
+	first := w.Load()
+	assert.Equal(t, 8080, first.Port)
+
+	var (
+		calls   int
+		changed []string
+		oldPort int
+	)
+	w.Subscribe(func(old, new *watchConfig, paths []string) {
+		calls++
+		oldPort = old.Port
+		changed = paths
+	})
+
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 9090\nmysql:\n  host: db2\nmodules:\n  kafkax: debug\n")
+	assert.Equal(t, 9090, w.Load().Port)
+	assert.Equal(t, 8080, oldPort)
+	assert.Equal(t, []string{"Modules.kafkax", "Mysql.Host", "Port"}, changed)
+	// 旧快照不会被修改
+	assert.Equal(t, "db1", first.Mysql.Host)
+
+	// 解析或校验失败时保留上一个版本
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: [\n")
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 0\nmysql:\n  host: db3\n")
+	assert.Equal(t, 9090, w.Load().Port)
+	assert.Equal(t, 1, calls)
+
+	// 内容没有变化时不通知
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 9090\nmysql:\n  host: db2\nmodules:\n  kafkax: debug\n")
+	assert.Equal(t, 1, calls)
+
+	_, err = NewWatch[watchConfig](newTestNacosx(t, newFakeConfigClient()))
+	assert.NotNil(t, err)
+	_, err = NewWatchFromContent[watchConfig]([]byte("name: demo\nport: -1\nmysql:\n  host: db\n"))
+	assert.ErrorContains(t, err, "port must be positive")
+}
+
+func TestWatchDropsStaleVersion(t *testing.T) {
+	client := newFakeConfigClient()
+	client.publish("DEFAULT_GROUP", "app.yaml", "name: demo\nport: 8080\nmysql:\n  host: db\n")
+	n := newTestNacosx(t, client)
+	n.config.Sources = []ConfigSource{{DataID: "common.yaml"}, {DataID: "app.yaml"}}
+	client.publish("DEFAULT_GROUP", "common.yaml", "name: common\n")
+
+	set, err := n.fetchSources(options{})
+	assert.Nil(t, err)
+	content, err := set.merged()
+	assert.Nil(t, err)
+	w, err := newWatch[watchConfig](LoadFromJsonBytes, content)
+	assert.Nil(t, err)
+
+	// 两次变化按 1、2 合并，但以 2、1 的顺序到达时旧版本被丢弃
+	older := set.update(1, "name: demo\nport: 9090\nmysql:\n  host: db\n")
+	newer := set.update(1, "name: demo\nport: 9091\nmysql:\n  host: db\n")
+	assert.Nil(t, w.updateVersion(newer.version, newer.merged))
+	assert.ErrorIs(t, w.updateVersion(older.version, older.merged), errStaleVersion)
+	assert.Equal(t, 9091, w.Load().Port)
+}
+
+func TestChangedPaths(t *testing.T) {
+	type Inner struct {
+		Hosts []string
+		Tags  map[string]int
+	}
+	type outer struct {
+		Inner
+		Name  string
+		Ptr   *Inner
+		token string
+	}
+
+	a := outer{Inner: Inner{Hosts: []string{"a"}, Tags: map[string]int{"x": 1, "y": 2}}, Name: "a", token: "1"}
+	b := outer{Inner: Inner{Hosts: []string{"a", "b"}, Tags: map[string]int{"x": 1, "z": 3}}, Name: "a", Ptr: &Inner{}, token: "2"}
+	assert.Equal(t, []string{"Hosts", "Ptr", "Tags.y", "Tags.z"}, ChangedPaths(a, b))
+	assert.Empty(t, ChangedPaths(&a, &a))
+}
+
+func TestNacosxLoad(t *testing.T) {
+	client := newFakeConfigClient()
+	n := newTestNacosx(t, client)
+
+	// 没有扩展名时按 yaml 解析
+	n.config.DataID = "app"
+	client.publish("DEFAULT_GROUP", "app", "name: demo\nport: ${PORT}\nmysql:\n  host: db\n")
+	t.Setenv("PORT", "8080")
+	var c watchConfig
+	assert.Nil(t, n.Load(&c, UseEnv()))
+	assert.Equal(t, 8080, c.Port)
+	assert.NotNil(t, n.Load(&watchConfig{}))
+
+	n.config.DataID = "app.json"
+	client.publish("DEFAULT_GROUP", "app.json", `{"name": "demo", "port": 9090, "mysql": {"host": "db"}}`)
+	c = watchConfig{}
+	assert.Nil(t, n.Load(&c))
+	assert.Equal(t, 9090, c.Port)
+
+	// 扩展名不是已知格式时按 yaml 解析
+	n.config.DataID = "com.example.order"
+	client.publish("DEFAULT_GROUP", "com.example.order", "name: demo\nport: 6060\nmysql:\n  host: db\n")
+	c = watchConfig{}
+	assert.Nil(t, n.Load(&c))
+	assert.Equal(t, 6060, c.Port)
+	assert.ErrorContains(t, n.Load(&c, WithFormat("conf")), "unrecognized config format")
+
+	n.config.DataID = "app.conf"
+	client.publish("DEFAULT_GROUP", "app.conf", "name = \"demo\"\nport = 7070\n[mysql]\nhost = \"db\"\n")
+	c = watchConfig{}
+	assert.Nil(t, n.Load(&c, WithFormat("toml")))
+	assert.Equal(t, 7070, c.Port)
+
+	w, err := NewWatch[watchConfig](n, WithFormat("toml"))
+	assert.Nil(t, err)
+	assert.Equal(t, 7070, w.Load().Port)
+}
+
+func TestWatchSources(t *testing.T) {
+	client := newFakeConfigClient()
+	client.publish("SHARED", "common.yaml", "name: common\nport: 8080\nmysql:\n  host: db\nmodules:\n  kafkax: warn\n  queue: warn\n")
+	client.publish("DEFAULT_GROUP", "app.json", `{"name": "demo", "modules": {"kafkax": "debug"}}`)
+
+	n := newTestNacosx(t, client)
+	n.config.Sources = []ConfigSource{{DataID: "common.yaml", Group: "SHARED"}, {DataID: "app.json"}}
+	assert.Equal(t, []ConfigSource{{DataID: "common.yaml", Group: "SHARED"}, {DataID: "app.json", Group: "DEFAULT_GROUP"}}, n.Sources())
+
+	var c watchConfig
+	assert.Nil(t, n.Load(&c))
+	assert.Equal(t, "demo", c.Name)
+	assert.Equal(t, 8080, c.Port)
+	assert.Equal(t, map[string]string{"kafkax": "debug", "queue": "warn"}, c.Modules)
+	m, err := n.LoadMap()
+	assert.Nil(t, err)
+	assert.Equal(t, "demo", m["name"])
+
+	w, err := NewWatch[watchConfig](n)
+	assert.Nil(t, err)
+	var changed []string
+	w.Subscribe(func(old, new *watchConfig, paths []string) {
+		changed = paths
+	})
+
+	// 任一配置源变化时整体重新合并
+	client.publish("SHARED", "common.yaml", "name: common\nport: 9090\nmysql:\n  host: db\nmodules:\n  kafkax: warn\n")
+	assert.Equal(t, 9090, w.Load().Port)
+	assert.Equal(t, "demo", w.Load().Name)
+	assert.Equal(t, []string{"Modules.queue", "Port"}, changed)
+
+	client.publish("DEFAULT_GROUP", "app.json", `{"name": "demo2"}`)
+	assert.Equal(t, "demo2", w.Load().Name)
+	assert.Equal(t, 9090, w.Load().Port)
+	assert.Equal(t, map[string]string{"kafkax": "warn"}, w.Load().Modules)
+
+	// 某个配置源解析失败时保留上一个版本
+	client.publish("SHARED", "common.yaml", "port: [\n")
+	assert.Equal(t, 9090, w.Load().Port)
+
+	n.config.Sources = append(n.config.Sources, ConfigSource{DataID: "missing.yaml"})
+	assert.ErrorContains(t, n.Load(&c), "missing.yaml@DEFAULT_GROUP")
+}
@@ -0,0 +1,222 @@
+package otelx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/betacats/go-core/utils/closes"
+	"github.com/betacats/go-core/utils/envx"
+	"github.com/betacats/go-core/utils/logx"
+)
+
+const (
+	// ExporterOtlpGrpc 通过 OTLP gRPC 上报
+	ExporterOtlpGrpc = "otlpgrpc"
+	// ExporterOtlpHttp 通过 OTLP HTTP 上报
+	ExporterOtlpHttp = "otlphttp"
+	// ExporterStdout 输出到标准输出，适用于本地调试
+	ExporterStdout = "stdout"
+	// ExporterNone 不上报，仍然生成 trace_id 用于日志关联
+	ExporterNone = "none"
+)
+
+// Config OpenTelemetry 配置
+type Config struct {
+	ServiceName    string
+	ServiceVersion string            `json:",optional"`
+	Env            string            `json:",optional"` // 为空时使用 envx.ENV()
+	Exporter       string            `json:",default=none,options=[otlpgrpc,otlphttp,stdout,none]"`
+	Endpoint       string            `json:",optional"` // OTLP 地址，如 otel-collector:4317，为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure       bool              `json:",optional"`
+	Headers        map[string]string `json:",optional"`
+	Sampler        *float64          `json:",optional,range=[0:1]"` // 采样率，为空时为 1，已有父 span 时跟随父 span 的采样决定
+	MetricInterval time.Duration     `json:",default=1m"`
+}
+
+var (
+	logger = logx.Module("otelx")
+
+	mu        sync.Mutex
+	shutdowns []func(ctx context.Context) error
+	register  sync.Once
+
+	// stdout 导出器的输出，测试中替换
+	stdout io.Writer = os.Stdout
+)
+
+// Init 按配置设置全局的 TracerProvider、MeterProvider 和传播器，
+// 并注册到 closes 在进程退出时刷新未上报的数据，再次调用时关闭之前创建的 provider
+func Init(ctx context.Context, c Config) error {
+	if c.ServiceName == "" {
+		return errors.New("otelx: service name is required")
+	}
+	if c.Env == "" {
+		c.Env = envx.ENV()
+	}
+	if c.Exporter == "" {
+		c.Exporter = ExporterNone
+	}
+	if c.MetricInterval <= 0 {
+		c.MetricInterval = time.Minute
+	}
+	sampler := 1.0
+	if c.Sampler != nil {
+		sampler = *c.Sampler
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", c.ServiceName),
+		attribute.String("service.version", c.ServiceVersion),
+		attribute.String("deployment.environment.name", c.Env),
+	))
+	if err != nil {
+		return err
+	}
+
+	spanExporter, metricExporter, err := newExporters(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampler))),
+	}
+	if spanExporter != nil {
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(spanExporter))
+	}
+	tp := sdktrace.NewTracerProvider(tpOpts...)
+
+	mpOpts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	if metricExporter != nil {
+		mpOpts = append(mpOpts, sdkmetric.WithReader(
+			sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(c.MetricInterval)),
+		))
+	}
+	mp := sdkmetric.NewMeterProvider(mpOpts...)
+
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("otel error", "error", err)
+	}))
+
+	// 重复 Init 时替换并关闭之前的 provider，避免旧的导出器继续运行
+	mu.Lock()
+	prev := shutdowns
+	shutdowns = []func(ctx context.Context) error{tp.Shutdown, mp.Shutdown}
+	mu.Unlock()
+	for _, fn := range prev {
+		if err = fn(ctx); err != nil {
+			logger.WarnContext(ctx, "shutdown previous otel provider failed", "error", err)
+		}
+	}
+	// Shutdown 后再次 Init 不重复注册
+	register.Do(func() {
+		closes.AddShutdown(closes.ModuleClose{
+			Name:     "otel",
+			Priority: closes.OtelPriority,
+			Func:     Shutdown,
+		})
+	})
+
+	logger.InfoContext(ctx, "otel initialized", "service", c.ServiceName, "exporter", c.Exporter, "sampler", sampler)
+	return nil
+}
+
+// Shutdown 刷新并关闭 Init 创建的 provider，已由 closes 在退出时调用
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	fns := shutdowns
+	shutdowns = nil
+	mu.Unlock()
+
+	var errs []error
+	for _, fn := range fns {
+		errs = append(errs, fn(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func newExporters(ctx context.Context, c Config) (sdktrace.SpanExporter, sdkmetric.Exporter, error) {
+	switch c.Exporter {
+	case ExporterNone:
+		return nil, nil, nil
+	case ExporterStdout:
+		return newExporterPair(ctx,
+			func() (sdktrace.SpanExporter, error) { return stdouttrace.New(stdouttrace.WithWriter(stdout)) },
+			func() (sdkmetric.Exporter, error) { return stdoutmetric.New(stdoutmetric.WithWriter(stdout)) },
+		)
+	case ExporterOtlpGrpc:
+		traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(c.Headers)}
+		metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithHeaders(c.Headers)}
+		if c.Endpoint != "" {
+			traceOpts = append(traceOpts, otlptracegrpc.WithEndpoint(c.Endpoint))
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+		}
+		return newExporterPair(ctx,
+			func() (sdktrace.SpanExporter, error) { return otlptracegrpc.New(ctx, traceOpts...) },
+			func() (sdkmetric.Exporter, error) { return otlpmetricgrpc.New(ctx, metricOpts...) },
+		)
+	case ExporterOtlpHttp:
+		traceOpts := []otlptracehttp.Option{otlptracehttp.WithHeaders(c.Headers)}
+		metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithHeaders(c.Headers)}
+		if c.Endpoint != "" {
+			traceOpts = append(traceOpts, otlptracehttp.WithEndpoint(c.Endpoint))
+			metricOpts = append(metricOpts, otlpmetrichttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+		}
+		return newExporterPair(ctx,
+			func() (sdktrace.SpanExporter, error) { return otlptracehttp.New(ctx, traceOpts...) },
+			func() (sdkmetric.Exporter, error) { return otlpmetrichttp.New(ctx, metricOpts...) },
+		)
+	default:
+		return nil, nil, fmt.Errorf("otelx: unknown exporter %q", c.Exporter)
+	}
+}
+
+// newExporterPair 依次创建 trace 和 metric 导出器，metric 导出器创建失败时关闭已创建的 trace 导出器
+func newExporterPair(
+	ctx context.Context,
+	newSpan func() (sdktrace.SpanExporter, error),
+	newMetric func() (sdkmetric.Exporter, error),
+) (sdktrace.SpanExporter, sdkmetric.Exporter, error) {
+	se, err := newSpan()
+	if err != nil {
+		return nil, nil, err
+	}
+	me, err := newMetric()
+	if err != nil {
+		return nil, nil, errors.Join(err, se.Shutdown(ctx))
+	}
+	return se, me, nil
+}
@@ -0,0 +1,132 @@
+package otelx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/betacats/go-core/utils/closes"
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+func TestInit(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() { _ = Shutdown(ctx) })
+
+	assert.NotNil(t, Init(ctx, Config{}))
+	assert.NotNil(t, Init(ctx, Config{ServiceName: "demo", Exporter: "zipkin"}))
+
+	assert.Nil(t, Init(ctx, Config{ServiceName: "demo"}))
+	_, span := otel.Tracer("test").Start(ctx, "sampled")
+	assert.True(t, span.SpanContext().IsValid())
+	assert.True(t, span.SpanContext().IsSampled())
+	span.End()
+	assert.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, otel.GetTextMapPropagator().Fields())
+
+	// 未采样时仍然生成 trace_id，便于日志关联
+	assert.Nil(t, Init(ctx, Config{ServiceName: "demo", Sampler: new(float64)}))
+	_, span = otel.Tracer("test").Start(ctx, "dropped")
+	assert.True(t, span.SpanContext().IsValid())
+	assert.False(t, span.SpanContext().IsSampled())
+	span.End()
+}
+
+func TestStdoutExporterFlushOnShutdown(t *testing.T) {
+	var buf bytes.Buffer
+	saved := stdout
+	stdout = &buf
+	t.Cleanup(func() { stdout = saved })
+
+	ctx := context.Background()
+	assert.Nil(t, Init(ctx, Config{ServiceName: "demo", Exporter: ExporterStdout}))
+	_, span := otel.Tracer("test").Start(ctx, "flushed")
+	span.End()
+	assert.NotContains(t, buf.String(), "flushed")
+
+	assert.Nil(t, Shutdown(ctx))
+	assert.Contains(t, buf.String(), `"Name":"flushed"`)
+	assert.Contains(t, buf.String(), "demo")
+}
+
+func TestConfigDefaults(t *testing.T) {
+	var c Config
+	assert.Nil(t, nacosx.LoadFromYamlBytes([]byte("serviceName: demo\n"), &c))
+	assert.Equal(t, "demo", c.ServiceName)
+	assert.Equal(t, ExporterNone, c.Exporter)
+	assert.Nil(t, c.Sampler)
+	assert.Equal(t, time.Minute, c.MetricInterval)
+
+	assert.NotNil(t, nacosx.LoadFromYamlBytes([]byte("serviceName: demo\nsampler: 2\n"), &c))
+	c = Config{}
+	assert.Nil(t, nacosx.LoadFromYamlBytes([]byte("serviceName: demo\nsampler: 0\n"), &c))
+	assert.Equal(t, 0.0, *c.Sampler)
+}
+
+func TestInitRegistersHookOnce(t *testing.T) {
+	ctx := context.Background()
+	count := func() int {
+		n := 0
+		for _, h := range closes.Hooks() {
+			if h.Name == "otel" {
+				n++
+			}
+		}
+		return n
+	}
+
+	assert.Nil(t, Init(ctx, Config{ServiceName: "demo"}))
+	assert.Nil(t, Shutdown(ctx))
+	assert.Nil(t, Init(ctx, Config{ServiceName: "demo"}))
+	t.Cleanup(func() { _ = Shutdown(ctx) })
+	assert.Equal(t, 1, count())
+}
+
+// fakeSpanExporter 记录是否被关闭
+type fakeSpanExporter struct {
+	sdktrace.SpanExporter
+	closed bool
+}
+
+func (e *fakeSpanExporter) Shutdown(context.Context) error {
+	e.closed = true
+	return nil
+}
+
+func TestNewExporterPairShutdownOnError(t *testing.T) {
+	ctx := context.Background()
+	se := &fakeSpanExporter{}
+	errMetric := errors.New("metric exporter failed")
+	_, _, err := newExporterPair(ctx,
+		func() (sdktrace.SpanExporter, error) { return se, nil },
+		func() (sdkmetric.Exporter, error) { return nil, errMetric },
+	)
+	assert.ErrorIs(t, err, errMetric)
+	assert.True(t, se.closed)
+}
+
+func TestReinitShutsDownPrevious(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() { _ = Shutdown(ctx) })
+
+	assert.Nil(t, Init(ctx, Config{ServiceName: "demo"}))
+	prev := otel.GetTracerProvider()
+	assert.Nil(t, Init(ctx, Config{ServiceName: "demo"}))
+
+	// 之前的 provider 已关闭，不再记录 span
+	_, span := prev.Tracer("test").Start(ctx, "stale")
+	assert.False(t, span.IsRecording())
+	_, span = otel.Tracer("test").Start(ctx, "current")
+	assert.True(t, span.IsRecording())
+	span.End()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, shutdowns, 2)
+}
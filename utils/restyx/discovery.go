@@ -0,0 +1,128 @@
+package restyx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+// DiscoveryHost 使用服务发现的 URL 主机名，如 http://nacos/order-service/orders/1，
+// 带端口的主机如 http://nacos:8848 不做服务发现
+const DiscoveryHost = "nacos"
+
+// maxDiscoveryAttempts 连接失败时最多尝试的实例数
+const maxDiscoveryAttempts = 3
+
+type (
+	// Picker 选择服务实例，*nacosx.Resolver 实现了该接口
+	Picker interface {
+		Pick(t nacosx.Target, opts ...nacosx.PickOption) (model.Instance, error)
+	}
+
+	// discoveryTransport 将 http://nacos/<service>/path 解析为服务实例的地址，连接失败时换一个实例重试
+	discoveryTransport struct {
+		next   http.RoundTripper
+		picker Picker
+		group  string
+	}
+)
+
+var _ Picker = (*nacosx.Resolver)(nil)
+
+// WithDiscovery 启用服务发现，每次请求时通过 picker 为 http://nacos/<service>/path 选择实例，
+// group 为服务分组，为空时使用 DEFAULT_GROUP
+func WithDiscovery(picker Picker, group string) Option {
+	return func(c *Client) {
+		c.discovery = &discoveryTransport{picker: picker, group: group}
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *discoveryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != DiscoveryHost {
+		return t.next.RoundTrip(req)
+	}
+
+	// 按转义后的路径切分，保留 %2F 等转义字符
+	escapedService, path, _ := strings.Cut(strings.TrimPrefix(req.URL.EscapedPath(), "/"), "/")
+	service, err := url.PathUnescape(escapedService)
+	if err != nil {
+		return nil, err
+	}
+	if service == "" {
+		return nil, fmt.Errorf("restyx: missing service name in %s", req.URL)
+	}
+	target := nacosx.Target{Service: service, Group: t.group}
+	span := trace.SpanFromContext(req.Context())
+
+	var failed []string
+	for attempt := 0; ; attempt++ {
+		inst, err := t.picker.Pick(target, nacosx.Exclude(failed...))
+		if err != nil {
+			return nil, err
+		}
+		addr := nacosx.InstanceAddr(inst)
+		span.SetAttributes(
+			attribute.String("peer.service", service),
+			attribute.String("server.address", inst.Ip),
+			attribute.Int("server.port", int(inst.Port)),
+			attribute.String("nacos.instance", addr),
+		)
+
+		outReq, err := rewrite(req, addr, "/"+path, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(outReq)
+		if err == nil || !isConnectError(err) || attempt+1 >= maxDiscoveryAttempts || !canRetry(req) {
+			return resp, err
+		}
+
+		failed = append(failed, addr)
+		span.AddEvent("retry on another instance", trace.WithAttributes(
+			attribute.String("nacos.instance", addr),
+			attribute.String("error", err.Error()),
+		))
+	}
+}
+
+// rewrite 返回发往 addr 的请求，rawPath 为转义后的路径，重试时重新获取请求体
+func rewrite(req *http.Request, addr, rawPath string, retry bool) (*http.Request, error) {
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	out := req.Clone(req.Context())
+	out.URL.Host = addr
+	out.URL.Path = path
+	out.URL.RawPath = rawPath
+	out.Host = ""
+	if retry && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+	return out, nil
+}
+
+// canRetry 没有请求体或请求体可以重新获取时才能重试
+func canRetry(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// isConnectError 连接阶段的错误，请求未发出，可以安全地换一个实例重试
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
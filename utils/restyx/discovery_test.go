@@ -0,0 +1,145 @@
+package restyx
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/betacats/go-core/utils/nacosx"
+)
+
+// fakePicker 依次返回 instances 中的实例
+type fakePicker struct {
+	mu        sync.Mutex
+	instances []model.Instance
+	targets   []nacosx.Target
+}
+
+func (p *fakePicker) Pick(t nacosx.Target, _ ...nacosx.PickOption) (model.Instance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets = append(p.targets, t)
+	if len(p.targets) > len(p.instances) {
+		return model.Instance{}, nacosx.ErrNoInstance
+	}
+	return p.instances[len(p.targets)-1], nil
+}
+
+func instanceOf(t *testing.T, rawURL string) model.Instance {
+	u, err := url.Parse(rawURL)
+	assert.Nil(t, err)
+	port, err := strconv.ParseUint(u.Port(), 10, 64)
+	assert.Nil(t, err)
+	return model.Instance{Ip: u.Hostname(), Port: port, Weight: 1, Healthy: true, Enable: true}
+}
+
+// closedAddr 返回一个没有监听的地址
+func closedAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := lis.Addr().String()
+	assert.Nil(t, lis.Close())
+	return "http://" + addr
+}
+
+func TestDiscovery(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+	}))
+	defer server.Close()
+
+	picker := &fakePicker{instances: []model.Instance{instanceOf(t, closedAddr(t)), instanceOf(t, server.URL)}}
+	client := NewClient(WithDiscovery(picker, "SHARED"))
+
+	req := client.R().SetContext(context.Background()).SetBody(map[string]any{"id": 1})
+	resp, err := client.Execute(req, http.MethodPost, "http://nacos/order-service/orders?page=2")
+	assert.Nil(t, err)
+	assert.Equal(t, `POST /orders?page=2 {"id":1}`, resp.String())
+	assert.Equal(t, []nacosx.Target{
+		{Service: "order-service", Group: "SHARED"},
+		{Service: "order-service", Group: "SHARED"},
+	}, picker.targets)
+
+	// 记录最终选择的实例和重试事件
+	attrs := make(map[attribute.Key]attribute.Value)
+	var events int
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() != trace.SpanKindClient {
+			continue
+		}
+		for _, kv := range span.Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		events = len(span.Events())
+	}
+	assert.Equal(t, server.Listener.Addr().String(), attrs["nacos.instance"].AsString())
+	assert.Equal(t, "order-service", attrs["peer.service"].AsString())
+	assert.Equal(t, 1, events)
+
+	// 其他主机不经过服务发现
+	resp, err = client.R().Get(server.URL + "/direct")
+	assert.Nil(t, err)
+	assert.Equal(t, "GET /direct", resp.String())
+	assert.Len(t, picker.targets, 2)
+}
+
+func TestDiscoveryNoInstance(t *testing.T) {
+	client := NewClient(WithDiscovery(&fakePicker{}, ""))
+	_, err := client.R().Get("http://nacos/order-service/orders")
+	assert.ErrorIs(t, err, nacosx.ErrNoInstance)
+
+	_, err = client.R().Get("http://nacos/")
+	assert.ErrorContains(t, err, "missing service name")
+}
+
+// roundTripFunc 记录发出的请求
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDiscoveryURL(t *testing.T) {
+	var got *url.URL
+	transport := &discoveryTransport{
+		picker: &fakePicker{instances: []model.Instance{{Ip: "10.0.0.1", Port: 8080}}},
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			got = req.URL
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}),
+	}
+
+	// 保留转义的路径
+	req := httptest.NewRequest(http.MethodGet, "http://nacos/file-service/files/a%2Fb?x=1", nil)
+	_, err := transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://10.0.0.1:8080/files/a%2Fb?x=1", got.String())
+	assert.Equal(t, "/files/a/b", got.Path)
+
+	// 带端口的 nacos 主机不做服务发现
+	req = httptest.NewRequest(http.MethodGet, "http://nacos:8848/nacos/v1/ns/instance/list", nil)
+	_, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://nacos:8848/nacos/v1/ns/instance/list", got.String())
+}
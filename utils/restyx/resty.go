@@ -21,7 +21,8 @@ type Option func(*Client)
 // Client 封装 resty.Client，兼容原有方法并集成 OTEL
 type Client struct {
 	*resty.Client
-	tracer trace.Tracer // OTEL 追踪器
+	tracer    trace.Tracer        // OTEL 追踪器
+	discovery *discoveryTransport // 服务发现，为空时不启用
 }
 
 // NewClient 创建带 OTEL 追踪的 resty 客户端
@@ -47,9 +48,14 @@ func NewClient(opts ...Option) *Client {
 	return client
 }
 
-// 配置默认 OTEL 传输层，自动追踪请求
+// 配置默认 OTEL 传输层，自动追踪请求，启用服务发现时在 OTEL 传输层内解析实例
 func (c *Client) setDefaultOTELTransport() {
-	transport := otelhttp.NewTransport(http.DefaultTransport,
+	var base http.RoundTripper = http.DefaultTransport
+	if c.discovery != nil {
+		c.discovery.next = base
+		base = c.discovery
+	}
+	transport := otelhttp.NewTransport(base,
 		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator(
 			propagation.TraceContext{},
 			propagation.Baggage{},